@@ -1,13 +1,19 @@
 package app
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"runtime"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/jessevdk/go-flags"
 	"github.com/mkock/autobot/config"
+	"github.com/mkock/autobot/country"
+	_ "github.com/mkock/autobot/country/all" // Registers all country plugins.
+	"github.com/mkock/autobot/dataprovider"
 	"github.com/mkock/autobot/extlookup"
 	"github.com/mkock/autobot/vehicle"
 )
@@ -19,6 +25,7 @@ var (
 	conf          config.Config      // Initialised in bootstrap.
 	store         *vehicle.Store     // Initialised in bootstrap.
 	lookupManager *extlookup.Manager // Initalised in bootstrap.
+	catalogue     *vehicle.Catalogue // Initialised in bootstrap.
 )
 
 // init (called automatically) sets up the CLI parser.
@@ -58,6 +65,79 @@ func loadConfig(fname string) (config.Config, error) {
 	return config.NewConfig(fname)
 }
 
+// providerNames returns the names of the given providers in alphabetical order.
+func providerNames(providers map[string]config.ProviderConfig) []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newLookupManager returns a lookup manager with the configured lookup services, followed by the lookup services of
+// each provider in alphabetical order of provider name. Provider lookup services get the provider's LookupPriority,
+// or, if that is zero, a priority below that of every configured lookup service.
+func newLookupManager(cnf config.Config, cat *vehicle.Catalogue) (*extlookup.Manager, error) {
+	mngr := extlookup.NewManager()
+	lowest := 0
+	for i, lookupCnf := range cnf.Lookups {
+		service, err := country.NewLookupService(lookupCnf, cat)
+		if err != nil {
+			return nil, fmt.Errorf("lookup service %d: %s", i+1, err)
+		}
+		if err = mngr.AddService(service, lookupCnf.Priority); err != nil {
+			return nil, fmt.Errorf("lookup service %d: %s", i+1, err)
+		}
+		if lookupCnf.Priority >= lowest {
+			lowest = lookupCnf.Priority + 1
+		}
+	}
+	for _, name := range providerNames(cnf.Providers) {
+		provCnf := cnf.Providers[name]
+		plugin, err := country.ForProvider(provCnf)
+		if err != nil {
+			return nil, fmt.Errorf("provider %s: %s", name, err)
+		}
+		priority := provCnf.LookupPriority
+		if priority == 0 {
+			priority = lowest
+		}
+		for _, service := range plugin.NewLookups(provCnf, cat) {
+			if err = mngr.AddService(service, priority); err != nil {
+				return nil, fmt.Errorf("provider %s: %s", name, err)
+			}
+		}
+	}
+	return mngr, nil
+}
+
+// migrateLastSynced hands the last synchronised filename of stores from before filenames were kept per provider over
+// to the provider that synchronised it. Back then, only DMR was supported, via FTP or the file system, so that's the
+// only provider it can belong to. If several providers qualify, the filename is left alone, and each provider starts
+// over with its latest file.
+func migrateLastSynced(providers map[string]config.ProviderConfig) error {
+	var candidates []string
+	for _, name := range providerNames(providers) {
+		provCnf := providers[name]
+		ptype, err := dataprovider.ProvTypeFromString(provCnf.Type)
+		if err != nil || (ptype != dataprovider.FtpProv && ptype != dataprovider.FsProv) {
+			continue
+		}
+		if provCnf.Country == "" || strings.EqualFold(provCnf.Country, "DK") {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) != 1 {
+		return nil
+	}
+	migrated, err := store.MigrateLastSynced(candidates[0])
+	if migrated {
+		log.Printf("Migrated the last synchronised file to provider %s\n", candidates[0])
+	}
+	return err
+}
+
 // bootstrap loads the app configuration and connects to the vehicle store.
 func bootstrap(cmd flags.Commander, args []string) error {
 	// Do not bootstrap the usual stuff when we are not dealing with a connected command. Instead, return early.
@@ -71,6 +151,11 @@ func bootstrap(cmd flags.Commander, args []string) error {
 		return err
 	}
 
+	// Load the brand and model catalogue.
+	if catalogue, err = vehicle.LoadCatalogue(conf.Catalogue.File); err != nil {
+		return err
+	}
+
 	// Connect to the vehicle store.
 	store = vehicle.NewStore(conf.MemStore, conf.Sync, os.Stdout)
 	if err := store.Open(); err != nil {
@@ -78,11 +163,15 @@ func bootstrap(cmd flags.Commander, args []string) error {
 	}
 	defer store.Close()
 
-	// Initialise and configure the lookup manager.
-	// @TODO: We need to loop over LookupConfigs and add a manager for each, dynamically.
-	lookupManager = extlookup.NewManager()
-	nrplade := extlookup.NewNrpladeService(conf.Providers["DMR"].LookupConfig)
-	lookupManager.AddService(nrplade)
+	// Hand the last synchronised filename of older stores over to its provider.
+	if err := migrateLastSynced(conf.Providers); err != nil {
+		return err
+	}
+
+	// Initialise the lookup manager with the configured lookup services and those of each provider.
+	if lookupManager, err = newLookupManager(conf, catalogue); err != nil {
+		return err
+	}
 
 	// Carry on with command execution.
 	return cmd.Execute(args)
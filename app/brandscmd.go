@@ -0,0 +1,65 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/mkock/autobot/vehicle"
+)
+
+// init registers the command with the parser.
+func init() {
+	var brandsCmd BrandsCommand
+	parser.AddCommand("brands", "list brands", "lists brands, models per brand or variants per model in the vehicle store", &brandsCmd)
+}
+
+// BrandsCommand lists the brands, models or variants of the vehicles in the store, with vehicle counts.
+type BrandsCommand struct {
+	Brand   string `short:"b" long:"brand" description:"Brand to list models for"`
+	Model   string `short:"m" long:"model" description:"Model to list variants for (requires --brand)"`
+	Rebuild bool   `short:"r" long:"rebuild" description:"Recount all vehicles in the store before listing"`
+}
+
+// Usage prints help text to the user.
+func (cmd *BrandsCommand) Usage() string {
+	return BrandsUsage
+}
+
+// Execute lists the requested brands, models or variants.
+func (cmd *BrandsCommand) Execute(opts []string) error {
+	if cmd.Rebuild {
+		if err := store.RebuildCatalog(); err != nil {
+			return err
+		}
+	}
+	var (
+		entries []vehicle.CatalogEntry
+		err     error
+	)
+	switch {
+	case cmd.Model != "" && cmd.Brand == "":
+		fmt.Println("Brands: --model requires --brand")
+		return nil
+	case cmd.Model != "":
+		entries, err = store.CatalogVariants(cmd.Brand, cmd.Model)
+	case cmd.Brand != "":
+		entries, err = store.CatalogModels(cmd.Brand)
+	default:
+		entries, err = store.CatalogBrands()
+	}
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No entries found")
+		return nil
+	}
+	for _, entry := range entries {
+		fmt.Printf("%-30s %8d  %d-%d\n", entry.Name, entry.Count, entry.FirstYear, entry.LastYear)
+	}
+	return nil
+}
+
+// IsConnected reports whether or not this command needs to connect to the VehicleStore.
+func (cmd *BrandsCommand) IsConnected() bool {
+	return true
+}
@@ -0,0 +1,67 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mkock/autobot/vehicle"
+)
+
+// init registers the command with the parser.
+func init() {
+	var catalogueCmd CatalogueCommand
+	parser.AddCommand("catalogue", "extend catalogue", "adds a brand, model or variant to the catalogue file", &catalogueCmd)
+}
+
+// CatalogueCommand adds a brand, model or variant to the brand and model catalogue, and saves it.
+type CatalogueCommand struct {
+	Brand     string   `short:"b" long:"brand" description:"Canonical brand name"`
+	Model     string   `short:"m" long:"model" description:"Canonical model name (requires --brand)"`
+	Variant   string   `short:"v" long:"variant" description:"Canonical variant name (requires --brand and --model)"`
+	Aliases   []string `short:"a" long:"alias" description:"Raw name that maps to the added entry (may be repeated)"`
+	ID        int      `long:"id" description:"Id of the added entry, derived from its name if omitted"`
+	Normalise bool     `short:"n" long:"normalise" description:"Re-normalise all vehicles in the store afterwards"`
+}
+
+// Usage prints help text to the user.
+func (cmd *CatalogueCommand) Usage() string {
+	return CatalogueUsage
+}
+
+// Execute adds the entry to the catalogue and saves it to the catalogue file.
+func (cmd *CatalogueCommand) Execute(opts []string) error {
+	var err error
+	switch {
+	case cmd.Brand == "":
+		return errors.New("catalogue: --brand is required")
+	case cmd.Variant != "" && cmd.Model == "":
+		return errors.New("catalogue: --variant requires --model")
+	case cmd.Variant != "":
+		err = catalogue.AddVariant(cmd.Brand, cmd.Model, vehicle.CatalogueVariant{ID: cmd.ID, Name: cmd.Variant, Aliases: cmd.Aliases})
+	case cmd.Model != "":
+		err = catalogue.AddModel(cmd.Brand, vehicle.CatalogueModel{ID: cmd.ID, Name: cmd.Model, Aliases: cmd.Aliases})
+	default:
+		catalogue.AddBrand(vehicle.CatalogueBrand{ID: cmd.ID, Name: cmd.Brand, Aliases: cmd.Aliases})
+	}
+	if err != nil {
+		return err
+	}
+	if err = catalogue.Save(); err != nil {
+		return err
+	}
+	fmt.Printf("Saved catalogue to %s\n", conf.Catalogue.File)
+	if !cmd.Normalise {
+		return nil
+	}
+	updated, err := store.Renormalise(catalogue)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Normalised %d vehicles\n", updated)
+	return nil
+}
+
+// IsConnected reports whether or not this command needs to connect to the VehicleStore.
+func (cmd *CatalogueCommand) IsConnected() bool {
+	return true
+}
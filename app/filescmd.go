@@ -0,0 +1,205 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/mkock/autobot/country"
+	"github.com/mkock/autobot/dataprovider"
+)
+
+// init registers the command and its subcommands with the parser.
+func init() {
+	var (
+		filesCmd     FilesCommand
+		listCmd      FilesListCommand
+		purgeCmd     FilesPurgeCommand
+		reprocessCmd FilesReprocessCommand
+	)
+	cmd, err := parser.AddCommand("files", "manage downloaded files", "lists, purges and reprocesses files in the download cache", &filesCmd)
+	if err != nil {
+		panic(err)
+	}
+	cmd.AddCommand("list", "list cached files", "lists the files in the download cache", &listCmd)
+	cmd.AddCommand("purge", "purge cached files", "removes files from the download cache", &purgeCmd)
+	cmd.AddCommand("reprocess", "reprocess a cached file", "synchronises the vehicle store with a cached file", &reprocessCmd)
+}
+
+// FilesCommand groups the subcommands for managing the download cache.
+type FilesCommand struct{}
+
+// Usage prints help text to the user.
+func (cmd *FilesCommand) Usage() string {
+	return FilesUsage
+}
+
+// FilesListCommand lists the files in the download cache.
+type FilesListCommand struct {
+	Provider string `short:"p" long:"provider" description:"Name of provider to list files for (default: all)"`
+}
+
+// Usage prints help text to the user.
+func (cmd *FilesListCommand) Usage() string {
+	return FilesUsage
+}
+
+// Execute lists the cached files of each provider, newest first.
+func (cmd *FilesListCommand) Execute(opts []string) error {
+	caches, err := providerCaches(cmd.Provider)
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedNames(caches) {
+		files, err := caches[name].Files()
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			status := "ok"
+			if !file.Complete {
+				status = "incomplete"
+			}
+			fmt.Printf("%-12s %-40s %12d  %s  %s\n", name, file.Name, file.Size, file.ModTime.Format("2006-01-02 15:04:05"), status)
+		}
+	}
+	return nil
+}
+
+// IsConnected reports whether or not this command needs to connect to the VehicleStore.
+func (cmd *FilesListCommand) IsConnected() bool {
+	return false
+}
+
+// FilesPurgeCommand removes files from the download cache.
+type FilesPurgeCommand struct {
+	Provider string `short:"p" long:"provider" description:"Name of provider to purge files for (default: all)"`
+	Args     struct {
+		File string `positional-arg-name:"file" description:"Name of the file to remove (default: all files)"`
+	} `positional-args:"yes"`
+}
+
+// Usage prints help text to the user.
+func (cmd *FilesPurgeCommand) Usage() string {
+	return FilesUsage
+}
+
+// Execute removes the given file, or all files, from the cache of each provider.
+func (cmd *FilesPurgeCommand) Execute(opts []string) error {
+	caches, err := providerCaches(cmd.Provider)
+	if err != nil {
+		return err
+	}
+	if cmd.Args.File != "" && cmd.Provider == "" {
+		return errors.New("use --provider when purging a single file")
+	}
+	for _, name := range sortedNames(caches) {
+		if err := caches[name].Purge(cmd.Args.File); err != nil {
+			return err
+		}
+	}
+	fmt.Println("Purged the download cache")
+	return nil
+}
+
+// IsConnected reports whether or not this command needs to connect to the VehicleStore.
+func (cmd *FilesPurgeCommand) IsConnected() bool {
+	return false
+}
+
+// FilesReprocessCommand synchronises the vehicle store with a file from the download cache.
+type FilesReprocessCommand struct {
+	Provider string `short:"p" long:"provider" required:"yes" description:"Name of provider that the file was downloaded from"`
+	Force    bool   `long:"force" description:"Reprocess the file even if it's older than the last synchronised file"`
+	Args     struct {
+		File string `positional-arg-name:"file" required:"yes" description:"Name of the cached file"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+// Usage prints help text to the user.
+func (cmd *FilesReprocessCommand) Usage() string {
+	return FilesUsage
+}
+
+// Execute parses the cached file and synchronises the vehicle store with it. The provider's last synchronised file is
+// left as-is, since the cached file may be older. Vehicles that are missing from the file are never marked as
+// deregistered. Since reprocessing overwrites the registration status, technical data and latest inspection of
+// existing vehicles, files that are older than the last synchronised file are refused unless "--force" is given.
+func (cmd *FilesReprocessCommand) Execute(opts []string) error {
+	provCnf, ok := conf.Providers[cmd.Provider]
+	if !ok {
+		return fmt.Errorf("No such provider: %s", cmd.Provider)
+	}
+	if lastSynced, _ := store.GetLastSynced(cmd.Provider); lastSynced != "" && !cmd.Force {
+		older, err := dataprovider.IsOlder(provCnf, cmd.Args.File, lastSynced)
+		if err != nil {
+			return fmt.Errorf("%s, use --force to reprocess it anyway", err)
+		}
+		if older {
+			return fmt.Errorf("%s is older than the last synchronised file %s and would roll back vehicle data, use --force to reprocess it anyway", cmd.Args.File, lastSynced)
+		}
+	}
+	cache := dataprovider.NewCache(provCnf.Cache, cmd.Provider)
+	if cache == nil {
+		return errors.New("no download cache configured, see [Cache] in the config file")
+	}
+	plugin, err := country.ForProvider(provCnf)
+	if err != nil {
+		return err
+	}
+	parser, err := plugin.NewParser(provCnf, catalogue)
+	if err != nil {
+		return err
+	}
+	src, err := cache.Open(cmd.Args.File, provCnf.DecompressConfig)
+	if err != nil {
+		return err
+	}
+
+	id := store.NewSyncOp(cmd.Provider, false)
+
+	vehicles, done := parser.LoadNew(src) // Closes src.
+	if err := store.Sync(id, vehicles, done); err != nil {
+		return err
+	}
+	fmt.Println(store.Status(id))
+
+	return nil
+}
+
+// IsConnected reports whether or not this command needs to connect to the VehicleStore.
+func (cmd *FilesReprocessCommand) IsConnected() bool {
+	return true
+}
+
+// providerCaches loads the configuration and returns the download cache of the provider with the given name, or of
+// all providers if the name is empty.
+func providerCaches(provider string) (map[string]*dataprovider.Cache, error) {
+	cnf, err := loadConfig(globalOpts.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	if cnf.Cache.Dir == "" {
+		return nil, errors.New("no download cache configured, see [Cache] in the config file")
+	}
+	caches := make(map[string]*dataprovider.Cache)
+	for name, provCnf := range cnf.Providers {
+		if provider == "" || name == provider {
+			caches[name] = dataprovider.NewCache(provCnf.Cache, name)
+		}
+	}
+	if len(caches) == 0 {
+		return nil, fmt.Errorf("No such provider: %s", provider)
+	}
+	return caches, nil
+}
+
+// sortedNames returns the provider names of the given caches in alphabetical order.
+func sortedNames(caches map[string]*dataprovider.Cache) []string {
+	names := make([]string, 0, len(caches))
+	for name := range caches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/mkock/autobot/importer"
+	"github.com/mkock/autobot/vehicle"
+)
+
+// init registers the command with the parser.
+func init() {
+	var importCmd ImportCommand
+	parser.AddCommand("import", "import vehicles", "imports vehicles from a CSV or NDJSON file using a column mapping", &importCmd)
+}
+
+// ImportCommand contains options for importing vehicles from a file with an ad-hoc layout.
+type ImportCommand struct {
+	File    string `short:"f" long:"file" required:"yes" description:"CSV or NDJSON file with vehicles"`
+	Mapping string `short:"m" long:"mapping" required:"yes" description:"Mapping file in TOML format"`
+}
+
+// Usage prints help text to the user.
+func (cmd *ImportCommand) Usage() string {
+	return ImportUsage
+}
+
+// Execute imports the vehicles in the file. Rows that can't be imported are reported, but don't stop the import.
+func (cmd *ImportCommand) Execute(opts []string) error {
+	mapping, err := importer.LoadMapping(cmd.Mapping)
+	if err != nil {
+		return err
+	}
+	var processed, synced, failed, rejected int
+	err = importer.New(mapping, catalogue).ReadFile(cmd.File, func(line int, veh vehicle.Vehicle, err error) error {
+		processed++
+		if err != nil {
+			failed++
+			fmt.Printf("Error: %s\n", err)
+			return nil
+		}
+		ok, err := store.SyncVehicle(veh)
+		if rejErr, isRejected := err.(vehicle.RejectedError); isRejected {
+			rejected++
+			fmt.Printf("Rejected: line %d: %s\n", line, rejErr.Rule)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if ok {
+			synced++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("Import %q from %s: processed %d, synced %d, failed %d, rejected %d", mapping.Name, cmd.File, processed, synced, failed, rejected)
+	fmt.Println(msg)
+	return store.Log(msg)
+}
+
+// IsConnected reports whether or not this command needs to connect to the VehicleStore.
+func (cmd *ImportCommand) IsConnected() bool {
+	return true
+}
@@ -3,6 +3,7 @@ package app
 import (
 	"fmt"
 
+	"github.com/mkock/autobot/locale"
 	"github.com/mkock/autobot/vehicle"
 )
 
@@ -14,10 +15,12 @@ func init() {
 
 // LookupCommand contains options for vehicle lookups using reg.nr. or VIN.
 type LookupCommand struct {
-	Country  string `short:"c" long:"country" description:"Country where vehicle is registered" required:"yes" choice:"DK" choice:"NO"`
-	VIN      string `short:"v" long:"vin" description:"VIN number to lookup, if any (will not synchronize data)"`
-	RegNr    string `short:"r" long:"regnr" description:"Registration number to lookup, if any (will not synchronize data)"`
-	Disabled bool   `short:"d" long:"disabled" description:"Include vehicle in result even if disabled"`
+	Country     string `short:"c" long:"country" description:"Country where vehicle is registered" required:"yes" choice:"DK" choice:"NO"`
+	VIN         string `short:"v" long:"vin" description:"VIN number to lookup, if any (will not synchronize data)"`
+	RegNr       string `short:"r" long:"regnr" description:"Registration number to lookup, if any (will not synchronize data)"`
+	Disabled    bool   `short:"d" long:"disabled" description:"Include vehicle in result even if disabled"`
+	Inspections bool   `short:"i" long:"inspections" description:"Include the inspection timeline and latest odometer value"`
+	Lang        string `short:"l" long:"lang" description:"Language of vehicle types, fuel types etc." default:"en" choice:"da" choice:"en" choice:"nb"`
 }
 
 // Usage prints help text to the user.
@@ -52,7 +55,33 @@ func (cmd *LookupCommand) Execute(opts []string) error {
 		fmt.Printf("No vehicle found with %s %s\n", desc, nr)
 		return nil
 	}
-	fmt.Println(veh.FlexString("\n", "  "))
+	lang, _ := locale.Parse(cmd.Lang)
+	fmt.Println(veh.TranslatedString("\n", "  ", lang))
+	if cmd.Inspections {
+		return printInspections(veh, lang)
+	}
+	return nil
+}
+
+// printInspections prints the inspection timeline of the given vehicle in the given language, followed by the latest
+// odometer value.
+func printInspections(veh vehicle.Vehicle, lang locale.Lang) error {
+	timeline, err := store.Inspections(veh)
+	if err != nil {
+		return err
+	}
+	if len(timeline) == 0 {
+		fmt.Println(lang.Label("No inspections found"))
+		return nil
+	}
+	fmt.Printf("%s:\n", lang.Label("Inspections"))
+	for _, insp := range timeline {
+		fmt.Printf("  %s\n", insp.TranslatedString(lang))
+	}
+	odo, date := timeline.LatestOdometer()
+	if !date.IsZero() {
+		fmt.Printf("%s: %d km (%s)\n", lang.Label("Latest odometer"), odo, date.Format("2006-01-02"))
+	}
 	return nil
 }
 
@@ -0,0 +1,32 @@
+package app
+
+import "fmt"
+
+// init registers the command with the parser.
+func init() {
+	var normaliseCmd NormaliseCommand
+	parser.AddCommand("normalise", "normalise vehicles", "re-normalises brand, model and variant names of all vehicles using the catalogue", &normaliseCmd)
+}
+
+// NormaliseCommand applies the brand and model catalogue to every vehicle in the store.
+type NormaliseCommand struct{}
+
+// Usage prints help text to the user.
+func (cmd *NormaliseCommand) Usage() string {
+	return NormaliseUsage
+}
+
+// Execute re-normalises all vehicles in the store.
+func (cmd *NormaliseCommand) Execute(opts []string) error {
+	updated, err := store.Renormalise(catalogue)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Normalised %d vehicles\n", updated)
+	return nil
+}
+
+// IsConnected reports whether or not this command needs to connect to the VehicleStore.
+func (cmd *NormaliseCommand) IsConnected() bool {
+	return true
+}
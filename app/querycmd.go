@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 
+	"github.com/mkock/autobot/locale"
 	"github.com/mkock/autobot/vehicle"
 )
 
@@ -15,11 +16,13 @@ func init() {
 
 // QueryCommand represents a query/search against the vehicle store.
 type QueryCommand struct {
-	Limit    uint   `short:"l" long:"limit" description:"Limit on number of vehicles to return" default:"0"`
-	Type     string `short:"t" long:"type" description:"Type of vehicle to filter by: Car|Bus|Van|Truck|Trailer|Unknown"`
-	Brand    string `short:"b" long:"brand" description:"Brand name to filter by, case insensitive"`
-	Model    string `short:"m" long:"model" description:"Model name to filter by, case insensitive"`
-	FuelType string `short:"f" long:"fuel-type" description:"Fuel-type to filter by, case insensitive"`
+	Limit     uint   `short:"l" long:"limit" description:"Limit on number of vehicles to return" default:"0"`
+	Type      string `short:"t" long:"type" description:"Type of vehicle to filter by: Car|Bus|Van|Truck|Trailer|Motorcycle|Moped|Tractor|MotorisedTool|Camper|SemiTrailer|Caravan|Quadricycle|Unknown"`
+	Brand     string `short:"b" long:"brand" description:"Brand name to filter by, case insensitive"`
+	Model     string `short:"m" long:"model" description:"Model name to filter by, case insensitive"`
+	FuelType  string `short:"f" long:"fuel-type" description:"Fuel-type to filter by: Petrol|Diesel|Electric|Hybrid|PluginHybrid|Hydrogen|Gas or a raw fuel-type, case insensitive"`
+	RegStatus string `short:"s" long:"reg-status" description:"Registration status to filter by: Registered|Deregistered|PreRegistered|Approved|Unknown"`
+	Lang      string `long:"lang" description:"Language of fuel types and registration statuses" default:"en" choice:"da" choice:"en" choice:"nb"`
 }
 
 // Usage prints help text to the user.
@@ -30,12 +33,15 @@ func (cmd *QueryCommand) Usage() string {
 // Execute performs a query against the vehicle store.
 func (cmd *QueryCommand) Execute(opts []string) error {
 	var out io.Writer = os.Stdout
+	lang, _ := locale.Parse(cmd.Lang)
 	q := vehicle.Query{
-		Limit:    int64(cmd.Limit),
-		Type:     cmd.Type,
-		Brand:    cmd.Brand,
-		Model:    cmd.Model,
-		FuelType: cmd.FuelType,
+		Limit:      int64(cmd.Limit),
+		Type:       cmd.Type,
+		Brand:      cmd.Brand,
+		Model:      cmd.Model,
+		FuelType:   cmd.FuelType,
+		RegStatus:  cmd.RegStatus,
+		Translator: lang,
 	}
 	return store.QueryTo(out, q)
 }
@@ -26,7 +26,7 @@ func (cmd *ServeCommand) Usage() string {
 
 // Execute runs the web server. It does not return unless the web server stops functioning.
 func (cmd *ServeCommand) Execute(opts []string) error {
-	api := webservice.New(store, lookupManager, conf)
+	api := webservice.New(store, lookupManager, catalogue, conf)
 	log.Printf("Serving on port %d\n", cmd.Port)
 	if err := api.Serve(cmd.Port, !cmd.NoSync); err != nil {
 		return err
@@ -5,8 +5,8 @@ import (
 	"log"
 
 	"github.com/mkock/autobot/config"
+	"github.com/mkock/autobot/country"
 	"github.com/mkock/autobot/dataprovider"
-	"github.com/mkock/autobot/dmr"
 )
 
 // init registers the command with the parser.
@@ -18,7 +18,7 @@ func init() {
 // SyncCommand contains options for synchronising the vehicle store with an external source.
 type SyncCommand struct {
 	Provider   string `short:"p" long:"provider" required:"yes" description:"Name of provider to sync with"`
-	SourceFile string `short:"f" long:"source-file" description:"DMR XML file in UTF-8 format"`
+	SourceFile string `short:"f" long:"source-file" description:"Local data file in the provider's format, ie. DMR XML in UTF-8"`
 	Debug      bool   `short:"d" long:"debug" description:"Debug: print CPU count, goroutine count and memory usage every 10 seconds"`
 }
 
@@ -40,35 +40,76 @@ func (cmd *SyncCommand) Execute(opts []string) error {
 	if provCnf, ok = conf.Providers[cmd.Provider]; !ok {
 		return fmt.Errorf("No such provider: %s", cmd.Provider)
 	}
+	plugin, err := country.ForProvider(provCnf)
+	if err != nil {
+		return err
+	}
 	if cmd.SourceFile == "" {
-		log.Printf("Using FTP data file at %q\n", provCnf.Host)
-		ptype = dataprovider.FtpProv
+		if ptype, err = dataprovider.ProvTypeFromString(provCnf.Type); err != nil {
+			return err
+		}
+		log.Printf("Using %s data file at %q\n", dataprovider.ProvTypeString(ptype), provCnf.Host)
 	} else {
 		log.Printf("Using local data file: %s\n", cmd.SourceFile)
 		ptype = dataprovider.FsProv
+		provCnf.Dir = "" // The source file is given by path, not by its name in the provider's directory.
 	}
-	prov := dataprovider.NewProvider(ptype, provCnf)
+	parser, err := plugin.NewParser(provCnf, catalogue)
+	if err != nil {
+		return err
+	}
+	prov := plugin.NewProvider(ptype, provCnf)
 	if err := prov.Open(); err != nil {
 		return err
 	}
-	fname, err := prov.CheckForLatest(cmd.SourceFile)
+	defer prov.Close()
+	// Without a local file, all files that are newer than the last synchronised one are synchronised in turn.
+	fname := cmd.SourceFile
+	if fname == "" {
+		fname, _ = store.GetLastSynced(cmd.Provider)
+	}
+	newer, err := prov.CheckForNewer(fname)
 	if err != nil {
 		return err
 	}
+	if len(newer) == 0 {
+		log.Print("No new stat file detected.")
+		return nil
+	}
+	for _, fname := range newer {
+		if err := cmd.syncFile(prov, cmd.Provider, provCnf.DetectDeregistered, parser, fname); err != nil {
+			return err
+		}
+		if cmd.SourceFile != "" {
+			continue
+		}
+		if err := store.SetLastSynced(cmd.Provider, fname); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncFile synchronises the vehicle store with a single file from the provider. Vehicles that are missing from the
+// file are marked as deregistered if detectDeregistered is set.
+func (cmd *SyncCommand) syncFile(prov dataprovider.DataProvider, provider string, detectDeregistered bool, parser country.Parser, fname string) error {
+	log.Printf("Synchronising %s...\n", fname)
 	src, err := prov.Provide(fname)
 	if err != nil {
 		return err
 	}
 	if src == nil {
-		log.Print("No stat file detected. Aborting.")
-		return nil
+		return fmt.Errorf("no stat file detected for %s", fname)
 	}
+	id := store.NewSyncOp(provider, detectDeregistered)
 
-	id := store.NewSyncOp(dataprovider.ProvTypeString(ptype))
-
-	dmrService := dmr.NewService()
-	vehicles, done := dmrService.LoadNew(src)
-	if err := store.Sync(id, vehicles, done); err != nil {
+	vehicles, done := parser.LoadNew(src)
+	err = store.Sync(id, vehicles, done)
+	// The parser has closed src already, but closing it again reports whether the provider failed.
+	if closeErr := src.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
 		return err
 	}
 	fmt.Println(store.Status(id))
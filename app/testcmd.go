@@ -0,0 +1,160 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/mkock/autobot/config"
+	"github.com/mkock/autobot/country"
+	"github.com/mkock/autobot/dataprovider"
+	"github.com/mkock/autobot/extlookup"
+	"github.com/mkock/autobot/vehicle"
+)
+
+// Results of a connectivity check.
+const (
+	testPass = "pass"
+	testFail = "fail"
+	testSkip = "skip"
+)
+
+// init registers the command with the parser.
+func init() {
+	var testCmd TestCommand
+	parser.AddCommand("test", "test integrations", "tests the connections to the vehicle store, providers and lookup services", &testCmd)
+}
+
+// TestCommand contains options for testing the integration with the vehicle store, each provider and each lookup
+// service.
+type TestCommand struct {
+	JSON bool `short:"j" long:"json" description:"Output the results as JSON"`
+}
+
+// testResult is the result of a single connectivity check.
+type testResult struct {
+	Check  string `json:"check"`
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Detail string `json:"detail"`
+}
+
+// Usage prints help text to the user.
+func (cmd *TestCommand) Usage() string {
+	return TestUsage
+}
+
+// Execute runs all checks and prints the results. It returns an error if any check failed.
+func (cmd *TestCommand) Execute(opts []string) error {
+	cnf, err := loadConfig(globalOpts.ConfigFile)
+	if err != nil {
+		return err
+	}
+	cat, err := vehicle.LoadCatalogue(cnf.Catalogue.File)
+	if err != nil {
+		return err
+	}
+	results := []testResult{testStore(cnf)}
+	for _, name := range providerNames(cnf.Providers) {
+		results = append(results, testProvider(name, cnf.Providers[name]))
+		results = append(results, testLookups(name, cnf.Providers[name], cat)...)
+	}
+	for i, lookupCnf := range cnf.Lookups {
+		service, err := country.NewLookupService(lookupCnf, cat)
+		if err != nil {
+			results = append(results, newTestResult("lookup", fmt.Sprintf("%s (%d)", lookupCnf.Name, i+1), "", err))
+			continue
+		}
+		results = append(results, testLookup(service, lookupCnf.LookupTestRegNr, "lookup service "+service.Name()))
+	}
+
+	failed := 0
+	for _, res := range results {
+		if res.Result == testFail {
+			failed++
+		}
+	}
+	if cmd.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, res := range results {
+			fmt.Printf("%-8s %-20s %-4s  %s\n", res.Check, res.Name, res.Result, res.Detail)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	return nil
+}
+
+// IsConnected reports whether or not this command needs to connect to the VehicleStore. The command connects by
+// itself, so a failing connection is reported like any other failing check.
+func (cmd *TestCommand) IsConnected() bool {
+	return false
+}
+
+// newTestResult returns the result of a check that failed with the given error, or passed with the given detail.
+func newTestResult(check, name, detail string, err error) testResult {
+	if err != nil {
+		return testResult{check, name, testFail, err.Error()}
+	}
+	return testResult{check, name, testPass, detail}
+}
+
+// testStore connects to the vehicle store.
+func testStore(cnf config.Config) testResult {
+	vs := vehicle.NewStore(cnf.MemStore, cnf.Sync, os.Stdout)
+	err := vs.Open()
+	defer vs.Close()
+	return newTestResult("store", fmt.Sprintf("%s:%d", cnf.MemStore.Host, cnf.MemStore.Port), "connected", err)
+}
+
+// testProvider connects to the provider, lists its files and reports the latest one.
+func testProvider(name string, provCnf config.ProviderConfig) testResult {
+	ptype, err := dataprovider.ProvTypeFromString(provCnf.Type)
+	if err != nil {
+		return newTestResult("provider", name, "", err)
+	}
+	if ptype == dataprovider.FsProv && provCnf.Dir == "" {
+		return testResult{"provider", name, testSkip, "local files are given by path"}
+	}
+	plugin, err := country.ForProvider(provCnf)
+	if err != nil {
+		return newTestResult("provider", name, "", err)
+	}
+	prov := plugin.NewProvider(ptype, provCnf)
+	if err = prov.Open(); err != nil {
+		return newTestResult("provider", name, "", err)
+	}
+	defer prov.Close()
+	latest, err := prov.CheckForLatest("")
+	return newTestResult("provider", name, fmt.Sprintf("%s, latest file: %s", dataprovider.ProvTypeString(ptype), latest), err)
+}
+
+// testLookups looks up the provider's test registration number with each of its lookup services.
+func testLookups(name string, provCnf config.ProviderConfig, cat *vehicle.Catalogue) []testResult {
+	plugin, err := country.ForProvider(provCnf)
+	if err != nil {
+		return nil // Reported by testProvider.
+	}
+	var results []testResult
+	for _, service := range plugin.NewLookups(provCnf, cat) {
+		results = append(results, testLookup(service, provCnf.LookupTestRegNr, name))
+	}
+	return results
+}
+
+// testLookup looks up the given test registration number with the lookup service. The check is skipped if there is no
+// test registration number, in which case "owner" names the configuration that lacks it.
+func testLookup(service extlookup.Lookupable, regNr, owner string) testResult {
+	if regNr == "" {
+		return testResult{"lookup", service.Name(), testSkip, "no LookupTestRegNr for " + owner}
+	}
+	veh, err := service.LookupRegNr(regNr)
+	detail := fmt.Sprintf("%s: %s %s", regNr, veh.Brand, veh.Model)
+	return newTestResult("lookup", service.Name(), detail, err)
+}
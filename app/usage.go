@@ -13,14 +13,22 @@ var (
   The web service offers these endpoints:
   - GET /                    responds with a service status
   - GET /vehiclestore/status responds with a status of the vehicle store
-  - GET /lookup              performs a vehicle lookup. Query params: country, hash, regnr or vin
+  - GET /lookup              performs a vehicle lookup. Query params: country, hash, regnr or vin, registered
   - PATCH /vehicle           enables/disables a vehicle based on the given operation
+  - GET /vehicle/inspections responds with the inspection timeline of a vehicle. Query params: as for /lookup
+  - GET /brands             responds with all brands, with vehicle counts and first/last registration years
+  - GET /brands/{brand}/models                   responds with all models of a brand
+  - GET /brands/{brand}/models/{model}/variants  responds with all variants of a model
   
   Example of a vehicle lookup:
   - GET /lookup?regnr=BK33877&country=dk
+  Add "registered=true" to leave out vehicles that have been deregistered.
+  Vehicle types, fuel types and registration statuses are translated according to the "lang" query parameter or the
+  Accept-Language header. Supported languages: da, en and nb.
   
   While the server is running, a scheduler will periodically check for new vehicle data from its source(s).
-  This happens according to the cron-style time expression given in the config file.`
+  This happens according to the cron-style time expression given in the config file.
+  Providers of type "fs" with "Watch = true" are synchronised as soon as a new file appears in their directory.`
 	SyncUsage = `Synchronise manually with a specific data source.
 
   The parameter "-p" (or "--provider") should specify the name of a provider to sync with.
@@ -28,10 +36,17 @@ var (
   Example:
     if the config file contains "[Providers.TEST]", among others, and you want to run a synchronisation with TEST,
     just use "-p TEST".
+  The provider's "Country" setting selects the plugin that parses the data, ie. "DK" for DMR or "NO" for
+  Statens vegvesen.
+  All files that are newer than the last synchronised one are synchronised in chronological order, so missed files
+  are caught up on. Each file is recorded as synchronised once it completes. The first synchronisation only uses the
+  latest file.
   Please be patient as synchronisation may take a long time.`
 	LookupUsage = `Perform a vehicle lookup based on registration or VIN.
 
-  Formatting is currently limited to a human readable format.`
+  Formatting is currently limited to a human readable format.
+  Use "--inspections" to include the inspection timeline and the latest known odometer value.
+  Use "--lang" to translate vehicle types, fuel types etc. into Danish (da), English (en) or Norwegian (nb).`
 	ClearUsage = `Clear the vehicle store of all data.
 
   You need to run the sync command again before any vehicle data will be available.`
@@ -50,5 +65,54 @@ var (
   
   Searches for vehicles using various criteria for text matching and sorting.
   For now, only an upper limit of the number of vehicles to return, is supported.
-  It's the intention to support multiple output formats, but currently, just a fixed CSV format is supported.`
+  It's the intention to support multiple output formats, but currently, just a fixed CSV format is supported.
+  Fuel types and registration statuses are translated according to "--lang".`
+	BrandsUsage = `List brands, models or variants of the vehicles in the store.
+
+  Without options, all brands are listed. Use "--brand" to list the models of a brand, and "--brand" together with
+  "--model" to list the variants of a model. Each entry shows the number of vehicles and the range of first
+  registration years. The counts are updated during sync; use "--rebuild" to recount all vehicles in the store.`
+	NormaliseUsage = `Normalise brand, model and variant names of all vehicles.
+
+  Applies the catalogue from the "[Catalogue]" section of the config file to every vehicle in the store.
+  Vehicles whose names change get a new hash, so they are replaced in the store and its indexes.
+  Run this after extending the catalogue file; new vehicles are normalised automatically during sync.`
+	CatalogueUsage = `Add a brand, model or variant to the catalogue.
+
+  Adds an entry to the catalogue and saves it back to the file given by "File" in the "[Catalogue]" section of the
+  config file. Use "--brand" alone to add a brand, together with "--model" to add a model to an existing brand, and
+  with "--model" and "--variant" to add a variant to an existing model. Adding an entry that already exists merges its
+  aliases ("--alias", which may be repeated) into it. Entries get an id derived from their name unless "--id" is given.
+  Use "--normalise" to re-normalise the vehicles that are already in the store.`
+	ImportUsage = `Import vehicles from a CSV or NDJSON file with an ad-hoc layout.
+
+  The parameter "-f" (or "--file") specifies the file to import, and "-m" (or "--mapping") a TOML file that maps
+  columns (CSV) or keys (NDJSON) to vehicle fields. Example mapping:
+    Name = "leasing"              # Stored as the source of each imported vehicle.
+    Delimiter = ";"
+    DateLayouts = ["02-01-2006"]  # Go reference time layouts.
+    Country = "DK"                # Used when a row has no country.
+    Type = "Car"                  # Used when a row has no known vehicle type.
+    [Columns]
+    RegNr = "Plate"
+    VIN = "Chassis"
+    Brand = "Make"
+    [Types]
+    "PB" = "Car"
+  Supported columns: Ident, Country, Type, RegNr, VIN, Brand, Model, Variant, FuelType, FirstRegDate, RegStatus.
+  Rows that can't be imported are reported with their line number and skipped.`
+	FilesUsage = `Manage the download cache.
+
+  Downloaded files are kept in the directory given by "Dir" in the "[Cache]" section of the config file, and reused
+  instead of being downloaded again as long as they match their checksum.
+  Use "files list" to list the cached files of all providers, or of a single provider with "-p".
+  Use "files purge" to remove all cached files, or "files purge -p NAME FILE" to remove a single file.
+  Use "files reprocess -p NAME FILE" to synchronise the vehicle store with a cached file again, ie. after a bug fix.
+  Reprocessing never marks vehicles as deregistered, and files that are older than the provider's last synchronised
+  file are refused unless "--force" is given, since they would roll back registration statuses.`
+	TestUsage = `Test the integration with the vehicle store, each provider and each lookup service.
+
+  Connects to the vehicle store, connects to each provider and finds the latest file that a synchronisation would
+  use, and looks up the registration number given by "LookupTestRegNr" with each lookup service (skipped if empty).
+  The results are printed as a table, or as JSON with "--json". The command fails if any check fails.`
 )
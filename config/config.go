@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -15,31 +16,149 @@ type Config struct {
 	MemStore   MemStoreConfig
 	WebService WebServiceConfig
 	Sync       SyncConfig
+	Catalogue  CatalogueConfig
+	Cache      CacheConfig
+	Lookups    []LookupServiceConfig
+}
+
+// CacheConfig contains configuration for the download cache. If Dir is set, downloaded files are kept in a
+// subdirectory per provider, and reused instead of being downloaded again. The newest MaxFiles files per provider are
+// kept, and files older than MaxAgeDays are removed. Zero means no limit.
+type CacheConfig struct {
+	Dir        string
+	MaxFiles   int
+	MaxAgeDays int
+}
+
+// CatalogueConfig contains configuration for the brand and model catalogue.
+type CatalogueConfig struct {
+	File string
 }
 
 // ProviderConfig contains configuration for the data provider.
+// Country selects the country plugin that handles the provider, ie. "DK", and defaults to "DK" if empty.
+// Type selects the data provider implementation, ie. "ftp", and defaults to "ftp" if empty.
+// VehicleTypes lists the names of the vehicle types to import from the provider, ie. "Car" or "Motorcycle".
+// Archive is an optional bucket that newly downloaded FTP files are uploaded to.
+// Watch makes the web service's scheduler synchronise new files as soon as they appear in Dir (fs provider type only).
+// DetectDeregistered marks vehicles of the provider's country that are missing from a data file as deregistered. Only
+// enable it for providers whose data files are full dumps of the registry.
+// LookupPriority is the priority of the provider's lookup services, see LookupServiceConfig. If zero, they are tried
+// after all lookup services that are configured separately.
+// Name and Cache are not part of the provider's section, but are filled in from the rest of the configuration.
 type ProviderConfig struct {
 	FtpConfig
+	HTTPConfig
+	SftpConfig
+	S3Config
+	DecompressConfig
+	ExecConfig
 	LookupConfig
+	Country            string
+	Type               string
+	VehicleTypes       []string
+	Watch              bool
+	DetectDeregistered bool
+	LookupPriority     int
+	Archive            S3Config
+	Name               string      `toml:"-"`
+	Cache              CacheConfig `toml:"-"`
 }
 
-// FtpConfig contains FTP connection configuration.
+// FtpConfig contains FTP connection configuration. FilePattern and TimeLayout select which files are data files and
+// how they are ordered: FilePattern is a regular expression with a named group "time", ie.
+// `^Stat-(?P<time>\d{8})\.zip$`, or a glob pattern with a "{time}" placeholder, ie. "Stat-{time}.zip", and
+// TimeLayout is the Go time layout of the timestamp, ie. "20060102". If FilePattern is empty, data files are named
+// FilePrefix followed by a timestamp in the "20060102-150405" layout.
 type FtpConfig struct {
-	Host       string
-	Port       int
-	User       string
-	Password   string
-	Dir        string
-	FilePrefix string
+	Host        string
+	Port        int
+	User        string
+	Password    string
+	Dir         string
+	FilePrefix  string
+	FilePattern string
+	TimeLayout  string
+}
+
+// HTTPConfig contains HTTP(S) connection configuration. URL points to either a directory listing (HTML) or an index
+// file (JSON) that lists the available data files. If Token is set, it's used for bearer authentication. Otherwise,
+// User and Password from the FtpConfig are used for basic authentication, if set.
+type HTTPConfig struct {
+	URL   string
+	Token string
+}
+
+// SftpConfig contains SFTP configuration. Host, Port, User, Password, Dir and FilePrefix from the FtpConfig are used
+// for the connection. KeyFile is a private key for public key authentication, which may be protected by KeyPassphrase.
+// The server's host key is verified against HostKey (in authorized_keys format) or the entries in KnownHostsFile.
+type SftpConfig struct {
+	KeyFile        string
+	KeyPassphrase  string
+	HostKey        string
+	KnownHostsFile string
+}
+
+// S3Config contains configuration for S3-compatible object storage. Endpoint is the base URL of the storage service,
+// ie. "https://s3.eu-west-1.amazonaws.com". PathStyle selects path-style bucket addressing, which is required by most
+// S3-compatible services such as MinIO. Objects are listed and stored below Prefix. LatestBy selects how the latest
+// object is found: "key" (default) compares the timestamp in the object names, "modified" compares LastModified.
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+	PathStyle bool
+	LatestBy  string
+}
+
+// DecompressConfig contains configuration for decompressing downloaded data files, which may be zip, gzip, bzip2 or
+// xz compressed. TempDir is the directory where each sync creates its own temporary directory for downloads (unless
+// the download cache is used) and temporary files, and defaults to the system's temporary directory. ZipEntry is a
+// pattern, ie. "*.xml", that selects the entries to read from zip files with multiple entries. All matching entries
+// are read one after another. If empty, the first entry is read.
+type DecompressConfig struct {
+	TempDir  string
+	ZipEntry string
 }
 
-// LookupConfig contains configuration for performing direct vehicle lookups via an API.
+// ExecConfig contains configuration for external executables that provide vehicles (exec provider type) or perform
+// lookups, see the subprocess package for the protocol. ExecCommand is run with ExecArgs, followed by the mode.
+// ExecTimeout and ExecLookupTimeout are in seconds, and default to an hour and 10 seconds. If ExecLookup is set, the
+// executable is also used as a lookup service for the provider's country.
+type ExecConfig struct {
+	ExecCommand       string
+	ExecArgs          []string
+	ExecTimeout       int
+	ExecLookup        bool
+	ExecLookupTimeout int
+}
+
+// LookupConfig contains configuration for performing direct vehicle lookups via an API. LookupTestRegNr is a
+// registration number that the "test" command looks up to verify the lookup service.
 type LookupConfig struct {
 	LookupSupported bool
 	LookupSecure    bool
 	LookupHost      string
 	LookupPath      string
 	LookupKey       string
+	LookupTestRegNr string
+}
+
+// LookupServiceConfig contains configuration for a lookup service that isn't tied to a provider. Type is either
+// "nrplade" (the default) or "exec". Country defaults to "DK". For vehicles that are not in the vehicle store, the
+// services that support the vehicle's country are tried in order of Priority, lowest first, until one of them finds
+// the vehicle. Name is required, and must be unique across all lookup services, including those of the providers,
+// which are named after the provider.
+type LookupServiceConfig struct {
+	LookupConfig
+	ExecConfig
+	Name     string
+	Country  string
+	Type     string
+	Priority int
 }
 
 // MemStoreConfig contains configuration for memory store / Redis.
@@ -75,14 +194,34 @@ func (d *date) UnmarshalText(text []byte) error {
 	return nil
 }
 
+// DefaultDeregisterThreshold is the fraction of vehicles that may be marked as deregistered by a single
+// synchronisation when the configuration doesn't say otherwise.
+const DefaultDeregisterThreshold = 0.05
+
 // SyncConfig contains configuration related to the actual synchronization algorithm.
+// DeregisterThreshold is the largest fraction of vehicles that may be marked as deregistered by a single
+// synchronisation, see ProviderConfig.DetectDeregistered. It defaults to DefaultDeregisterThreshold if left out.
 type SyncConfig struct {
-	SyncedFileString string
-	VehicleMap       string
-	VINSortedSet     string
-	RegNrSortedSet   string
-	HistorySortedSet string
-	EarliestRegDate  date
+	SyncedFileString    string
+	VehicleMap          string
+	VINSortedSet        string
+	RegNrSortedSet      string
+	HistorySortedSet    string
+	InspectionSortedSet string
+	CatalogHash         string
+	SeenSet             string
+	EarliestRegDate     date
+	DeregisterThreshold float64
+	Rules               RulesConfig
+}
+
+// RulesConfig contains the data-quality rules that vehicles must satisfy before they are added to the vehicle store.
+type RulesConfig struct {
+	RejectMissingVIN    bool
+	RejectMalformedVIN  bool
+	RejectVINCheckDigit bool
+	RejectFutureRegDate bool
+	BrandBlocklist      []string
 }
 
 // NewConfig returns a app configuration struct, loaded from a TOML file.
@@ -94,9 +233,34 @@ func NewConfig(fname string) (Config, error) {
 	if file == "" {
 		return conf, fmt.Errorf("No such file: %s", fname)
 	}
-	if _, err := toml.DecodeFile(fname, &conf); err != nil {
+	meta, err := toml.DecodeFile(fname, &conf)
+	if err != nil {
 		return conf, err
 	}
+	// Provider sections contain the keys of every provider type, so a misspelled key would otherwise go unnoticed.
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		keys := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			keys[i] = key.String()
+		}
+		return conf, fmt.Errorf("unknown configuration keys: %s", strings.Join(keys, ", "))
+	}
+	if !meta.IsDefined("Sync", "DeregisterThreshold") {
+		conf.Sync.DeregisterThreshold = DefaultDeregisterThreshold
+	}
+	for i, lookupCnf := range conf.Lookups {
+		if lookupCnf.Name == "" {
+			return conf, fmt.Errorf("lookup service %d: Name is required", i+1)
+		}
+	}
+	for name, provCnf := range conf.Providers {
+		if strings.EqualFold(provCnf.Type, "exec") && provCnf.DetectDeregistered {
+			// Executables may provide deltas, so missing vehicles aren't necessarily deregistered.
+			return conf, fmt.Errorf("provider %s: DetectDeregistered is not supported by the exec provider type", name)
+		}
+		provCnf.Name, provCnf.Cache = name, conf.Cache
+		conf.Providers[name] = provCnf
+	}
 	return conf, nil
 }
 
@@ -8,12 +8,85 @@ import (
 var cnfTpl = `[Providers]
 
 [Providers.NAME]
+# Country selects the country plugin that parses the provider's data files. Supported: DK (DMR XML) and
+# NO (Statens vegvesen CSV).
+Country = "DK"
+# Type selects how data files are fetched. Supported: ftp, http, sftp, s3, fs (files in the local directory Dir) and
+# exec (vehicles as NDJSON from ExecCommand).
+Type = "ftp"
 Host = ""
 Port = 21
+# User and Password are also used for basic authentication with the http provider type, and for the sftp provider type.
 User = ""
 Password = ""
 Dir = "/"
 FilePrefix = "ESStatistikListeModtag-"
+# Optional pattern for data file names: a regular expression with a named group "time" in single quotes, ie.
+# '^Stat-(?P<time>\d{8})\.zip$', or a glob pattern with a "{time}" placeholder, ie. "Stat-{time}.zip". TimeLayout is
+# the Go time layout of the timestamp, ie. "20060102". Leave FilePattern empty for FilePrefix followed by a
+# "20060102-150405" timestamp.
+FilePattern = ""
+TimeLayout = ""
+# URL of a directory listing or JSON index of data files, and an optional bearer token (http provider type only).
+URL = ""
+Token = ""
+# Private key and host key verification (sftp provider type only). Either HostKey ("ssh-ed25519 AAAA...") or
+# KnownHostsFile must be set. Use Port = 22 for sftp.
+KeyFile = ""
+KeyPassphrase = ""
+HostKey = ""
+KnownHostsFile = ""
+# S3-compatible object storage (s3 provider type only). Use PathStyle = true for MinIO and similar services.
+# LatestBy is either "key" (timestamp in the object name) or "modified" (LastModified of the object).
+Endpoint = ""
+Region = ""
+Bucket = ""
+Prefix = ""
+AccessKey = ""
+SecretKey = ""
+PathStyle = false
+LatestBy = "key"
+# External executable that writes vehicles as NDJSON (exec provider type), and optionally performs lookups
+# (ExecLookup). Timeouts are in seconds. A run that fails or times out aborts the sync. Since executables may write
+# deltas, DetectDeregistered can't be enabled for the exec provider type.
+ExecCommand = ""
+ExecArgs = []
+ExecTimeout = 3600
+ExecLookup = false
+ExecLookupTimeout = 10
+# Directory for downloads and temporary files (defaults to the system's temporary directory), and a pattern that
+# selects the entries to read from zip files with multiple entries, ie. "*.xml" (defaults to the first entry).
+# Matching entries are read one after another, as if they were a single file.
+TempDir = ""
+ZipEntry = ""
+# Vehicle types to import. Supported: Car, Bus, Van, Truck, Trailer, Motorcycle, Moped, Tractor, MotorisedTool,
+# Camper, SemiTrailer, Caravan, Quadricycle and Unknown.
+VehicleTypes = ["Car", "Bus", "Van", "Truck", "Trailer"]
+# Direct lookups of vehicles that are not in the vehicle store.
+LookupSecure = true
+LookupHost = ""
+LookupPath = ""
+LookupKey = ""
+# Priority of the provider's lookup services among those in [[Lookups]]. 0 means after all of them.
+LookupPriority = 0
+# Registration number that "autobot test" looks up to verify the lookup service. Leave empty to skip.
+LookupTestRegNr = ""
+# Synchronise new files as soon as they appear in Dir, instead of waiting for the schedule (fs provider type only).
+Watch = false
+# Mark vehicles of the provider's country that are missing from a data file as deregistered. Only enable this for
+# providers whose data files are complete dumps of the registry, not deltas.
+DetectDeregistered = false
+
+# Optional bucket that newly downloaded FTP files are uploaded to, so other environments can use the s3 provider
+# type instead of the FTP server. Leave Bucket empty to disable.
+[Providers.NAME.Archive]
+Endpoint = ""
+Region = ""
+Bucket = ""
+Prefix = ""
+AccessKey = ""
+SecretKey = ""
+PathStyle = false
 
 [MemStore]
 Host = "127.0.0.1"
@@ -32,7 +105,52 @@ VehicleMap = "autobot_vehicles"
 VINSortedSet = "autobot_vin_index"
 RegNrSortedSet = "autobot_regnr_index"
 HistorySortedSet = "autobot_history"
+InspectionSortedSet = "autobot_inspections"
+CatalogHash = "autobot_catalog"
+SeenSet = "autobot_seen"
 EarliestRegDate = ""
+# Vehicles that disappear from the data file of a provider with DetectDeregistered are marked as deregistered, unless
+# more than DeregisterThreshold (a fraction between 0 and 1) of the provider's vehicles would be affected. Defaults to
+# 0.05 if left out. 0 means that nothing is marked as soon as a single vehicle is missing.
+DeregisterThreshold = 0.05
+
+[Sync.Rules]
+# Data-quality rules applied to all vehicles, whether they come from a provider or an external lookup.
+# Note that the VIN check digit is only mandatory in North America, so most European vehicles will fail that rule.
+# Brands in BrandBlocklist are matched regardless of case, whitespace and punctuation.
+RejectMissingVIN = true
+RejectMalformedVIN = false
+RejectVINCheckDigit = false
+RejectFutureRegDate = true
+BrandBlocklist = []
+
+[Catalogue]
+# TOML file that maps raw brand, model and variant names to canonical names and stable ids. Leave empty to only
+# prettify brand names.
+File = ""
+
+[Cache]
+# Directory where downloaded files are kept for reuse, with a subdirectory per provider. Leave empty to disable.
+# The newest MaxFiles files per provider are kept, and files older than MaxAgeDays are removed (0 means no limit).
+Dir = ""
+MaxFiles = 5
+MaxAgeDays = 90
+
+# Lookup services for vehicles that are not in the vehicle store, in addition to those of the providers. The services
+# that support a vehicle's country are tried in order of Priority (lowest first) until one of them finds the vehicle;
+# provider lookup services are tried last unless the provider sets LookupPriority. Name is required and must be
+# unique, including among provider lookup services, which are named after the provider. Type is "nrplade" (DK only)
+# or "exec" (see ExecCommand above). Uncomment and repeat for each service.
+# [[Lookups]]
+# Name = "nrplade"
+# Country = "DK"
+# Type = "nrplade"
+# Priority = 1
+# LookupSecure = true
+# LookupHost = ""
+# LookupPath = ""
+# LookupKey = ""
+# LookupTestRegNr = ""
 `
 
 // WriteEmptyConf writes a new, empty configuration file to the filename with the given name.
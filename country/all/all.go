@@ -0,0 +1,11 @@
+// Package all registers every available country plugin. Import it for its side effects:
+//
+//	import _ "github.com/mkock/autobot/country/all"
+//
+// Adding a new country only requires a new plugin package and an import here.
+package all
+
+import (
+	_ "github.com/mkock/autobot/country/dk" // Denmark.
+	_ "github.com/mkock/autobot/country/no" // Norway.
+)
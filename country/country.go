@@ -0,0 +1,124 @@
+package country
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mkock/autobot/config"
+	"github.com/mkock/autobot/dataprovider"
+	"github.com/mkock/autobot/extlookup"
+	"github.com/mkock/autobot/importer"
+	"github.com/mkock/autobot/vehicle"
+)
+
+// Parser is the interface for parsers that turn data files from a provider into vehicles. LoadNew delivers the parsed
+// vehicles on the first channel and closes it once all vehicles have been delivered. It then sends nil on the second
+// channel if the entire data file was parsed, or the error that stopped it, ie. a truncated download.
+type Parser interface {
+	LoadNew(io.ReadCloser) (chan vehicle.Vehicle, chan error)
+}
+
+// Plugin is the interface that each country plugin must implement. A plugin provides everything that is needed for
+// synchronising with, and looking up vehicles from, the vehicle registry of a specific country. Plugins don't need
+// to handle "exec" providers, which deliver vehicles as NDJSON and may perform lookups, see ForProvider.
+type Plugin interface {
+	Country() vehicle.RegCountry
+	NewProvider(ptype int, cnf config.ProviderConfig) dataprovider.DataProvider
+	NewParser(cnf config.ProviderConfig, cat *vehicle.Catalogue) (Parser, error)
+	NewLookups(cnf config.ProviderConfig, cat *vehicle.Catalogue) []extlookup.Lookupable
+}
+
+// plugins contains all registered plugins, by country code. Plugins register themselves via their init functions.
+var plugins = map[string]Plugin{}
+
+// Register makes a country plugin available under the given country code, ie. "DK". It panics if a plugin has
+// already been registered for the country, as that's a programming error.
+func Register(code string, plugin Plugin) {
+	code = strings.ToUpper(code)
+	if _, ok := plugins[code]; ok {
+		panic(fmt.Sprintf("country: plugin for %s registered twice", code))
+	}
+	plugins[code] = plugin
+}
+
+// Find returns the plugin registered for the given country code.
+func Find(code string) (Plugin, error) {
+	plugin, ok := plugins[strings.ToUpper(code)]
+	if !ok {
+		return nil, fmt.Errorf("country: no plugin for country %q, available: %s", code, strings.Join(Codes(), ", "))
+	}
+	return plugin, nil
+}
+
+// ForProvider returns the plugin selected by the given provider configuration. Providers that don't specify a
+// country default to DK, which was the only supported country before plugins were introduced. For exec providers,
+// and providers with an exec lookup service, the plugin is wrapped so the executable is used the same way for every
+// country.
+func ForProvider(cnf config.ProviderConfig) (Plugin, error) {
+	code := cnf.Country
+	if code == "" {
+		code = "DK"
+	}
+	plugin, err := Find(code)
+	if err != nil {
+		return nil, err
+	}
+	if ptype, _ := dataprovider.ProvTypeFromString(cnf.Type); ptype == dataprovider.ExecProv || cnf.ExecLookup {
+		return execPlugin{plugin}, nil
+	}
+	return plugin, nil
+}
+
+// execPlugin wraps the plugin of a country for providers that run an external executable, which delivers vehicles
+// as NDJSON instead of the registry's own format, and may also perform lookups.
+type execPlugin struct {
+	Plugin
+}
+
+// NewParser returns an NDJSON parser for exec providers, and the parser of the country plugin otherwise.
+func (plugin execPlugin) NewParser(cnf config.ProviderConfig, cat *vehicle.Catalogue) (Parser, error) {
+	if ptype, _ := dataprovider.ProvTypeFromString(cnf.Type); ptype != dataprovider.ExecProv {
+		return plugin.Plugin.NewParser(cnf, cat)
+	}
+	return importer.NewParser(importer.VehicleMapping(cnf.Name, plugin.Country().String()), cat)
+}
+
+// NewLookups returns the lookup services of the country plugin, followed by an exec lookup service if the provider
+// is configured with one. The exec lookup service is named after the provider, with an "-exec" suffix if the
+// country plugin also provides lookup services.
+func (plugin execPlugin) NewLookups(cnf config.ProviderConfig, cat *vehicle.Catalogue) []extlookup.Lookupable {
+	services := plugin.Plugin.NewLookups(cnf, cat)
+	if !cnf.ExecLookup {
+		return services
+	}
+	name := cnf.Name
+	if len(services) > 0 {
+		name += "-exec"
+	}
+	return append(services, extlookup.NewExecService(name, plugin.Country(), cnf.ExecConfig, cat))
+}
+
+// Codes returns the country codes of all registered plugins, sorted alphabetically.
+func Codes() []string {
+	codes := make([]string, 0, len(plugins))
+	for code := range plugins {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// NewLookupService returns the configured lookup service for the country of the configuration, which defaults to DK.
+func NewLookupService(cnf config.LookupServiceConfig, cat *vehicle.Catalogue) (extlookup.Lookupable, error) {
+	code := cnf.Country
+	if code == "" {
+		code = "DK"
+	}
+	plugin, err := Find(code)
+	if err != nil {
+		return nil, err
+	}
+	return extlookup.NewService(cnf, plugin.Country(), cat)
+}
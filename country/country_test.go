@@ -0,0 +1,54 @@
+package country
+
+import (
+	"testing"
+
+	"github.com/mkock/autobot/config"
+	"github.com/mkock/autobot/dataprovider"
+	"github.com/mkock/autobot/extlookup"
+	"github.com/mkock/autobot/vehicle"
+)
+
+type testPlugin struct{}
+
+func (testPlugin) Country() vehicle.RegCountry { return vehicle.DK }
+func (testPlugin) NewProvider(int, config.ProviderConfig) dataprovider.DataProvider {
+	return nil
+}
+func (testPlugin) NewParser(config.ProviderConfig, *vehicle.Catalogue) (Parser, error) {
+	return nil, nil
+}
+func (testPlugin) NewLookups(config.ProviderConfig, *vehicle.Catalogue) []extlookup.Lookupable {
+	return nil
+}
+
+func TestFindPlugin(t *testing.T) {
+	Register("xx", testPlugin{})
+	defer delete(plugins, "XX")
+	if _, err := Find("XX"); err != nil {
+		t.Fatalf("Expected %v but got %v", nil, err)
+	}
+	if _, err := ForProvider(config.ProviderConfig{Country: "xx"}); err != nil {
+		t.Fatalf("Expected %v but got %v", nil, err)
+	}
+	if _, err := Find("YY"); err == nil {
+		t.Fatalf("Expected error but got %v", err)
+	}
+}
+
+func TestForExecProvider(t *testing.T) {
+	Register("xx", testPlugin{})
+	defer delete(plugins, "XX")
+	cnf := config.ProviderConfig{Country: "xx", Type: "exec", Name: "script", ExecConfig: config.ExecConfig{ExecLookup: true}}
+	plugin, err := ForProvider(cnf)
+	if err != nil {
+		t.Fatalf("Expected %v but got %v", nil, err)
+	}
+	if parser, err := plugin.NewParser(cnf, nil); parser == nil || err != nil {
+		t.Fatalf("Expected %v but got %v", "NDJSON parser", err)
+	}
+	services := plugin.NewLookups(cnf, nil)
+	if len(services) != 1 || services[0].Name() != "script" {
+		t.Fatalf("Expected %v but got %v", "exec lookup service named script", services)
+	}
+}
@@ -0,0 +1,47 @@
+package dk
+
+import (
+	"github.com/mkock/autobot/config"
+	"github.com/mkock/autobot/country"
+	"github.com/mkock/autobot/dataprovider"
+	"github.com/mkock/autobot/dmr"
+	"github.com/mkock/autobot/extlookup"
+	"github.com/mkock/autobot/vehicle"
+)
+
+// init registers the plugin.
+func init() {
+	country.Register("DK", plugin{})
+}
+
+// plugin is the country plugin for Denmark. It synchronises with DMR, the Danish Motor Registry, and performs
+// direct lookups via nrpla.de.
+type plugin struct{}
+
+// Country returns the country of registration for vehicles handled by the plugin.
+func (plugin) Country() vehicle.RegCountry {
+	return vehicle.DK
+}
+
+// NewProvider returns a data provider of the requested type. DMR data is available via FTP.
+func (plugin) NewProvider(ptype int, cnf config.ProviderConfig) dataprovider.DataProvider {
+	return dataprovider.NewProvider(ptype, cnf)
+}
+
+// NewParser returns a DMR parser that imports the vehicle types listed in the provider configuration.
+func (plugin) NewParser(cnf config.ProviderConfig, cat *vehicle.Catalogue) (country.Parser, error) {
+	types, err := vehicle.TypesFromStrings(cnf.VehicleTypes)
+	if err != nil {
+		return nil, err
+	}
+	return dmr.NewService(types, cat), nil
+}
+
+// NewLookups returns the nrpla.de lookup service, configured by the provider configuration and named after the
+// provider, unless the provider has no LookupHost.
+func (plugin) NewLookups(cnf config.ProviderConfig, cat *vehicle.Catalogue) []extlookup.Lookupable {
+	if cnf.LookupHost == "" {
+		return nil
+	}
+	return []extlookup.Lookupable{extlookup.NewNrpladeService(cnf.Name, cnf.LookupConfig, cat)}
+}
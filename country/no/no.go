@@ -0,0 +1,44 @@
+package no
+
+import (
+	"github.com/mkock/autobot/config"
+	"github.com/mkock/autobot/country"
+	"github.com/mkock/autobot/dataprovider"
+	"github.com/mkock/autobot/extlookup"
+	"github.com/mkock/autobot/vegvesen"
+	"github.com/mkock/autobot/vehicle"
+)
+
+// init registers the plugin.
+func init() {
+	country.Register("NO", plugin{})
+}
+
+// plugin is the country plugin for Norway. It synchronises with the bulk data (CSV) from the Norwegian vehicle
+// registry, published by Statens vegvesen. Direct lookups are not supported, so Norwegian vehicles are only served
+// from the vehicle store.
+type plugin struct{}
+
+// Country returns the country of registration for vehicles handled by the plugin.
+func (plugin) Country() vehicle.RegCountry {
+	return vehicle.NO
+}
+
+// NewProvider returns a data provider of the requested type.
+func (plugin) NewProvider(ptype int, cnf config.ProviderConfig) dataprovider.DataProvider {
+	return dataprovider.NewProvider(ptype, cnf)
+}
+
+// NewParser returns a CSV parser that imports the vehicle types listed in the provider configuration.
+func (plugin) NewParser(cnf config.ProviderConfig, cat *vehicle.Catalogue) (country.Parser, error) {
+	types, err := vehicle.TypesFromStrings(cnf.VehicleTypes)
+	if err != nil {
+		return nil, err
+	}
+	return vegvesen.NewService(cnf.Name, types, cat), nil
+}
+
+// NewLookups returns no lookup services, since there is no supported lookup API for Norwegian vehicles.
+func (plugin) NewLookups(cnf config.ProviderConfig, cat *vehicle.Catalogue) []extlookup.Lookupable {
+	return nil
+}
@@ -0,0 +1,235 @@
+package dataprovider
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mkock/autobot/config"
+)
+
+// checksumExt is the extension of the files that contain the SHA-256 checksums of cached files.
+const checksumExt = ".sha256"
+
+// Cache is a directory of downloaded files from a single provider. Each file is stored with its checksum, so a
+// file is only reused if it was downloaded completely and hasn't changed since.
+type Cache struct {
+	dir      string
+	maxFiles int
+	maxAge   time.Duration
+}
+
+// CachedFile describes a file in the cache. Complete reports whether the file was downloaded completely, ie. whether
+// its checksum has been stored. The file itself is only checked against the checksum when it's used.
+type CachedFile struct {
+	Name     string
+	Size     int64
+	ModTime  time.Time
+	Checksum string
+	Complete bool
+}
+
+// NewCache returns the cache of the provider with the given name, or nil if no cache directory is configured.
+func NewCache(conf config.CacheConfig, provider string) *Cache {
+	if conf.Dir == "" {
+		return nil
+	}
+	return &Cache{
+		dir:      filepath.Join(conf.Dir, provider),
+		maxFiles: conf.MaxFiles,
+		maxAge:   time.Duration(conf.MaxAgeDays) * 24 * time.Hour,
+	}
+}
+
+// path returns the location of the file with the given name in the cache.
+func (c *Cache) path(fname string) string {
+	return filepath.Join(c.dir, filepath.Base(fname))
+}
+
+// checksum returns the SHA-256 checksum of the file with the given path.
+func checksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verify returns the stored checksum of the file with the given name, and whether the file matches it.
+func (c *Cache) verify(fname string) (string, bool) {
+	stored, err := ioutil.ReadFile(c.path(fname) + checksumExt)
+	if err != nil {
+		return "", false
+	}
+	sum, err := checksum(c.path(fname))
+	return string(stored), err == nil && sum == string(stored)
+}
+
+// add stores the checksum of the newly downloaded file with the given name, and removes files that have expired.
+func (c *Cache) add(fname string) error {
+	sum, err := checksum(c.path(fname))
+	if err != nil {
+		return err
+	}
+	if err = ioutil.WriteFile(c.path(fname)+checksumExt, []byte(sum), 0644); err != nil {
+		return err
+	}
+	return c.prune(fname)
+}
+
+// Files returns the files in the cache, from newest to oldest. Partial downloads are included, but aren't complete.
+func (c *Cache) Files() ([]CachedFile, error) {
+	entries, err := ioutil.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var files []CachedFile
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), checksumExt) || strings.HasSuffix(entry.Name(), metaExt) {
+			continue
+		}
+		sum, err := ioutil.ReadFile(c.path(entry.Name()) + checksumExt)
+		files = append(files, CachedFile{entry.Name(), entry.Size(), entry.ModTime(), string(sum), err == nil})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime.After(files[j].ModTime) })
+	return files, nil
+}
+
+// Open returns the decompressed contents of the cached file with the given name, if it matches its checksum.
+func (c *Cache) Open(fname string, conf config.DecompressConfig) (io.ReadCloser, error) {
+	if _, valid := c.verify(fname); !valid {
+		return nil, fmt.Errorf("%s is not in the cache, or doesn't match its checksum", fname)
+	}
+	return c.open(fname, conf)
+}
+
+// open returns the decompressed contents of the cached file with the given name, without verifying it.
+func (c *Cache) open(fname string, conf config.DecompressConfig) (io.ReadCloser, error) {
+	file, err := os.Open(c.path(fname))
+	if err != nil {
+		return nil, err
+	}
+	return decompress(file, conf)
+}
+
+// Purge removes the file with the given name from the cache, or all files if the name is empty.
+func (c *Cache) Purge(fname string) error {
+	if fname == "" {
+		return os.RemoveAll(c.dir)
+	}
+	if _, err := os.Stat(c.path(fname)); err != nil {
+		return err
+	}
+	for _, path := range []string{c.path(fname), c.path(fname) + checksumExt, metaFile(c.path(fname))} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// prune removes the files that exceed the configured number of files or maximum age, except the given file.
+func (c *Cache) prune(keep string) error {
+	files, err := c.Files()
+	if err != nil {
+		return err
+	}
+	for i, file := range files {
+		expired := c.maxFiles > 0 && i >= c.maxFiles || c.maxAge > 0 && time.Since(file.ModTime) > c.maxAge
+		if !expired || file.Name == filepath.Base(keep) {
+			continue
+		}
+		log.Printf("Removing %s from the cache...\n", file.Name)
+		if err = c.Purge(file.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// provide returns the decompressed contents of the file with the given name. If the file is in the cache and matches
+// its checksum, it's reused. Otherwise, "fetch" is called to download the file to the given location first. The
+// download metadata of a file that doesn't match its checksum is removed first, so a corrupt file is never resumed or
+// reported as unchanged. Without
+// a cache, files are downloaded to a new temporary directory, which is removed when the returned reader is closed, so
+// concurrent syncs never share a download.
+func provide(c *Cache, fname string, conf config.DecompressConfig, fetch func(local string) error) (io.ReadCloser, error) {
+	var (
+		local   string
+		cleanup = func() error { return nil }
+	)
+	if c != nil {
+		local = c.path(fname)
+		sum, valid := c.verify(fname)
+		if valid {
+			log.Printf("Using cached copy of %s\n", fname)
+			return c.open(fname, conf)
+		}
+		if sum != "" {
+			// The file was downloaded completely but has changed since, so its download metadata can't be trusted.
+			if err := os.Remove(metaFile(local)); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+		if err := os.MkdirAll(c.dir, 0755); err != nil {
+			return nil, err
+		}
+	} else {
+		dir, err := ioutil.TempDir(tempDir(conf), "autobot-")
+		if err != nil {
+			return nil, err
+		}
+		local = filepath.Join(dir, filepath.Base(fname))
+		cleanup = func() error { return os.RemoveAll(dir) }
+	}
+	if err := fetch(local); err != nil {
+		cleanup()
+		return nil, err
+	}
+	if c != nil {
+		if err := c.add(fname); err != nil {
+			return nil, err
+		}
+	}
+	r, err := os.Open(local)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	rc, err := decompress(r, conf)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	return &readCloser{rc, []func() error{rc.Close, cleanup}}, nil
+}
+
+// copyToFile downloads the source to the local file with the given path.
+func copyToFile(local string, src io.Reader, fname string) error {
+	w, err := os.Create(local)
+	if err != nil {
+		return err
+	}
+	log.Printf("Downloading %s...\n", fname)
+	_, err = io.Copy(w, src)
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package dataprovider
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mkock/autobot/config"
+)
+
+func TestCacheProvide(t *testing.T) {
+	cache := NewCache(config.CacheConfig{Dir: t.TempDir(), MaxFiles: 2}, "dmr")
+	fetches := 0
+	fetch := func(fname string) func(string) error {
+		return func(local string) error {
+			fetches++
+			return ioutil.WriteFile(local, []byte(fname), 0644)
+		}
+	}
+	for _, fname := range []string{"Stat-20190101-000000.txt", "Stat-20190101-000000.txt"} {
+		rc, err := provide(cache, fname, config.DecompressConfig{}, fetch(fname))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		if string(b) != fname {
+			t.Fatalf("Expected %v but got %v", fname, string(b))
+		}
+	}
+	// The second call uses the cached copy.
+	if fetches != 1 {
+		t.Fatalf("Expected %v but got %v", 1, fetches)
+	}
+
+	// Corrupted files are downloaded again, without their download metadata.
+	if err := ioutil.WriteFile(cache.path("Stat-20190101-000000.txt"), []byte("corrupt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeMeta(cache.path("Stat-20190101-000000.txt"), downloadMeta{Complete: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Open("Stat-20190101-000000.txt", config.DecompressConfig{}); err == nil {
+		t.Fatalf("Expected error but got %v", err)
+	}
+	rc, err := provide(cache, "Stat-20190101-000000.txt", config.DecompressConfig{}, func(local string) error {
+		if meta := readMeta(local); meta.Complete {
+			t.Fatalf("Expected %v but got %v", downloadMeta{}, meta)
+		}
+		return fetch("Stat-20190101-000000.txt")(local)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+	if fetches != 2 {
+		t.Fatalf("Expected %v but got %v", 2, fetches)
+	}
+
+	// Failed downloads aren't complete.
+	if _, err = provide(cache, "Stat-20190102-000000.txt", config.DecompressConfig{}, func(local string) error {
+		ioutil.WriteFile(local, []byte("partial"), 0644)
+		return errors.New("connection reset")
+	}); err == nil {
+		t.Fatalf("Expected error but got %v", err)
+	}
+	files, err := cache.Files()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Complete == files[1].Complete {
+		t.Fatalf("Expected %v but got %v", "1 complete and 1 incomplete file", files)
+	}
+}
+
+func TestCachePrune(t *testing.T) {
+	cache := NewCache(config.CacheConfig{Dir: t.TempDir(), MaxFiles: 2, MaxAgeDays: 30}, "dmr")
+	os.MkdirAll(cache.dir, 0755)
+	names := []string{"Stat-20190101-000000.txt", "Stat-20190201-000000.txt", "Stat-20190301-000000.txt", "Stat-20190401-000000.txt"}
+	for i, name := range names {
+		if err := ioutil.WriteFile(cache.path(name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// The first file is too old, and the others are a day apart.
+		modTime := time.Now().Add(time.Duration(i-len(names)) * 24 * time.Hour)
+		if i == 0 {
+			modTime = time.Now().Add(-60 * 24 * time.Hour)
+		}
+		os.Chtimes(cache.path(name), modTime, modTime)
+		if err := cache.add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, _ := cache.Files()
+	if len(files) != 2 || files[0].Name != names[3] || files[1].Name != names[2] {
+		t.Fatalf("Expected %v but got %v", names[2:], files)
+	}
+	if err := cache.Purge(names[3]); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cache.path(names[3]) + checksumExt); !os.IsNotExist(err) {
+		t.Fatalf("Expected %v but got %v", "no checksum file", err)
+	}
+	if err := cache.Purge(""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Dir(cache.path(names[2]))); !os.IsNotExist(err) {
+		t.Fatalf("Expected %v but got %v", "no cache directory", err)
+	}
+}
+
+func TestProvideWithoutCache(t *testing.T) {
+	conf := config.DecompressConfig{TempDir: t.TempDir()}
+	rc, err := provide(nil, "Stat-20190101-000000.txt", conf, func(local string) error {
+		return ioutil.WriteFile(local, []byte("vehicles"), 0644)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := ioutil.ReadDir(conf.TempDir); len(entries) != 1 {
+		t.Fatalf("Expected %v but got %v", 1, len(entries))
+	}
+	rc.Close()
+	if entries, _ := ioutil.ReadDir(conf.TempDir); len(entries) != 0 {
+		t.Fatalf("Expected %v but got %v", 0, len(entries))
+	}
+}
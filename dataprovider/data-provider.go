@@ -1,30 +1,33 @@
 package dataprovider
 
 import (
-	"archive/zip"
 	"fmt"
 	"io"
 	"log"
 	"os"
-	"path/filepath"
+	"strings"
 
 	"github.com/mkock/autobot/config"
 )
 
-// TmpFileLoc contains the temporary location of the ZIP file before unzipping.
-const TmpFileLoc = "/tmp/vehicledata.zip"
-
 // Constants for picking DataProvider implementations.
 const (
 	FtpProv = iota
 	FsProv
+	HTTPProv
+	SftpProv
+	S3Prov
+	ExecProv
 )
 
 // DataProvider is the interface for implementations that fetches files for Autobot to parse.
+// CheckForNewer returns all files that are newer than the given one in chronological order, so files that were missed
+// can be synchronised in turn. If the given file name is empty, only the latest file is returned.
 type DataProvider interface {
 	Open() error
 	Close() error
 	CheckForLatest(string) (string, error)
+	CheckForNewer(string) ([]string, error)
 	Provide(string) (io.ReadCloser, error)
 }
 
@@ -35,51 +38,70 @@ func ProvTypeString(ptype int) string {
 		return "ftp"
 	case FsProv:
 		return "fs"
+	case HTTPProv:
+		return "http"
+	case SftpProv:
+		return "sftp"
+	case S3Prov:
+		return "s3"
+	case ExecProv:
+		return "exec"
 	default:
 		return ""
 	}
 }
 
+// ProvTypeFromString returns the provider type with the given string representation. An empty string is interpreted
+// as FtpProv, which is the default provider type.
+func ProvTypeFromString(str string) (int, error) {
+	switch strings.ToLower(str) {
+	case "", "ftp":
+		return FtpProv, nil
+	case "fs":
+		return FsProv, nil
+	case "http", "https":
+		return HTTPProv, nil
+	case "sftp":
+		return SftpProv, nil
+	case "s3":
+		return S3Prov, nil
+	case "exec":
+		return ExecProv, nil
+	default:
+		return 0, fmt.Errorf("no such provider type: %s", str)
+	}
+}
+
 // NewProvider returns a new provider of the requested type (implementation).
 func NewProvider(ptype int, config config.ProviderConfig) DataProvider {
 	switch ptype {
 	case FtpProv:
-		return NewFtpProvider(config.FtpConfig)
+		prov := NewFtpProvider(config.FtpConfig, config.DecompressConfig)
+		if config.Archive.Bucket != "" {
+			prov.SetArchive(config.Archive)
+		}
+		prov.SetCache(NewCache(config.Cache, config.Name))
+		return prov
 	case FsProv:
-		return NewFileProvider()
+		return NewFileProvider(config)
+	case HTTPProv:
+		return NewHTTPProvider(config)
+	case SftpProv:
+		return NewSftpProvider(config)
+	case S3Prov:
+		return NewS3Provider(config)
+	case ExecProv:
+		return NewExecProvider(config)
 	default:
 		log.Fatalf("No such provider: %d (%s)", ptype, ProvTypeString(ptype))
 		return nil
 	}
 }
 
-// isZipped checks if the given file name has the ".zip" extension.
-func isZipped(fname string) bool {
-	return filepath.Ext(fname) == ".zip"
-}
-
-// unzip extracts the source ReadCloser by writing to a zip file, and then extracting it into a new io.ReadCloser.
-func unzip(src io.ReadCloser) (io.ReadCloser, error) {
-	// Write the zip file to a temporary file on disk.
-	file, err := os.Create(TmpFileLoc)
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		file.Close()
-		src.Close()
-	}()
-	if _, err := io.Copy(file, src); err != nil {
-		return nil, err
-	}
-	// Open the temporary file and extract the first zipped file.
-	r, err := zip.OpenReader(TmpFileLoc)
-	if err != nil {
-		return nil, err
-	}
-	if len(r.File) == 0 {
-		return nil, fmt.Errorf("unzip %s: empty zip file", TmpFileLoc)
+// tempDir returns the configured directory for downloads and temporary files, or the system's temporary directory.
+func tempDir(conf config.DecompressConfig) string {
+	if conf.TempDir != "" {
+		return conf.TempDir
 	}
-	f := r.File[0] // Assuming that the zip file contains only a single file.
-	return f.Open()
+	return os.TempDir()
 }
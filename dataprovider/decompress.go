@@ -0,0 +1,195 @@
+package dataprovider
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/mkock/autobot/config"
+	"github.com/ulikunitz/xz"
+)
+
+// Magic bytes that identify the supported compression formats.
+var (
+	zipMagic   = []byte("PK\x03\x04")
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// readCloser is an io.ReadCloser that reads from a (decompressing) reader and closes the underlying resources.
+type readCloser struct {
+	io.Reader
+	closers []func() error
+}
+
+// Close closes all underlying resources and returns the first error.
+func (rc *readCloser) Close() error {
+	var err error
+	for _, closer := range rc.closers {
+		if closeErr := closer(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// decompress returns a reader that decompresses the source, if it's compressed. The compression format is detected
+// from the first bytes of the source: zip, gzip, bzip2 and xz are supported, and anything else is returned as-is.
+// Closing the returned reader closes the source.
+func decompress(src io.ReadCloser, conf config.DecompressConfig) (io.ReadCloser, error) {
+	br := bufio.NewReader(src)
+	magic, _ := br.Peek(len(xzMagic)) // Shorter sources are checked as-is.
+	var (
+		r   io.Reader
+		err error
+	)
+	switch {
+	case bytes.HasPrefix(magic, zipMagic):
+		return unzip(src, br, conf)
+	case bytes.HasPrefix(magic, gzipMagic):
+		r, err = gzip.NewReader(br)
+	case bytes.HasPrefix(magic, bzip2Magic):
+		r = bzip2.NewReader(br)
+	case bytes.HasPrefix(magic, xzMagic):
+		r, err = xz.NewReader(br)
+	default:
+		r = br
+	}
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	return &readCloser{r, []func() error{src.Close}}, nil
+}
+
+// unzip returns a reader for the zip entries selected by the ZipEntry pattern, or the first entry if no pattern is
+// configured. Multiple matching entries are read one after another, see zipReader. Zip files require random access, so sources other than local files are first copied to a temporary
+// directory, which is removed when the returned reader is closed.
+func unzip(src io.ReadCloser, br *bufio.Reader, conf config.DecompressConfig) (io.ReadCloser, error) {
+	closers := []func() error{src.Close}
+	file, ok := src.(*os.File)
+	if !ok {
+		dir, err := ioutil.TempDir(tempDir(conf), "autobot-")
+		if err != nil {
+			src.Close()
+			return nil, err
+		}
+		closers = append(closers, func() error { return os.RemoveAll(dir) })
+		if file, err = spool(br, dir); err != nil {
+			(&readCloser{closers: closers}).Close()
+			return nil, err
+		}
+		closers = append([]func() error{file.Close}, closers...)
+	}
+	rc, err := openZipEntries(file, conf.ZipEntry)
+	if err != nil {
+		(&readCloser{closers: closers}).Close()
+		return nil, err
+	}
+	return &readCloser{rc, append([]func() error{rc.Close}, closers...)}, nil
+}
+
+// spool copies the source to a new file in the given directory, and returns the file.
+func spool(src io.Reader, dir string) (*os.File, error) {
+	file, err := ioutil.TempFile(dir, "download-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.Copy(file, src); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// openZipEntries opens the entries in the zip file whose base names match the pattern, in the order they are stored.
+// An empty pattern selects the first file entry only, so use "*" to read all entries.
+func openZipEntries(file *os.File, pattern string) (io.ReadCloser, error) {
+	finfo, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(file, finfo.Size())
+	if err != nil {
+		return nil, err
+	}
+	var entries []*zip.File
+	for _, entry := range zr.File {
+		if entry.FileInfo().IsDir() {
+			continue
+		}
+		if pattern == "" {
+			entries = append(entries, entry)
+			break
+		}
+		if ok, err := path.Match(pattern, path.Base(entry.Name)); err != nil {
+			return nil, fmt.Errorf("unzip %s: invalid ZipEntry pattern: %s", file.Name(), err)
+		} else if ok {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 && pattern != "" {
+		return nil, fmt.Errorf("unzip %s: no entry matches %s", file.Name(), pattern)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("unzip %s: empty zip file", file.Name())
+	}
+	return &zipReader{entries: entries}, nil
+}
+
+// zipReader reads zip entries one after another, as if they were a single file. A line break is inserted between
+// entries, so the last line of an entry isn't joined with the first line of the next one.
+type zipReader struct {
+	entries []*zip.File
+	cur     io.ReadCloser
+	sep     bool // Whether a line break is due before the next entry.
+}
+
+// Read reads from the current entry, and opens the next entry when the current one is exhausted.
+func (zr *zipReader) Read(p []byte) (int, error) {
+	for {
+		if zr.cur == nil {
+			if len(zr.entries) == 0 {
+				return 0, io.EOF
+			}
+			if zr.sep && len(p) > 0 {
+				zr.sep = false
+				p[0] = '\n'
+				return 1, nil
+			}
+			rc, err := zr.entries[0].Open()
+			if err != nil {
+				return 0, err
+			}
+			zr.cur, zr.entries = rc, zr.entries[1:]
+		}
+		n, err := zr.cur.Read(p)
+		if err == io.EOF {
+			err = zr.cur.Close()
+			zr.cur, zr.sep = nil, len(zr.entries) > 0
+			if n > 0 || err != nil {
+				return n, err
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+// Close closes the current entry.
+func (zr *zipReader) Close() error {
+	if zr.cur == nil {
+		return nil
+	}
+	err := zr.cur.Close()
+	zr.cur, zr.entries = nil, nil
+	return err
+}
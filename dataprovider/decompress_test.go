@@ -0,0 +1,120 @@
+package dataprovider
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mkock/autobot/config"
+	"github.com/ulikunitz/xz"
+)
+
+// bzip2Data is "vehicles" compressed with bzip2, since the standard library can't compress bzip2.
+var bzip2Data = []byte("\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\x45\x26\x0d\x1f\x00\x00\x03\x81\x80\x0a\x64\x09\x00\x20\x00\x31\x0c\x01\x06\x9b\x47\xc1\x82\x94\x5d\xc9\x14\xe1\x42\x41\x14\x98\x34\x7c")
+
+// zipData returns a zip file with the given entries, in order.
+func zipData(t *testing.T, entries ...string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(filepath.Base(name)))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	var gz, xzBuf bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	gw.Write([]byte("vehicles"))
+	gw.Close()
+	xw, _ := xz.NewWriter(&xzBuf)
+	xw.Write([]byte("vehicles"))
+	xw.Close()
+
+	dir := t.TempDir()
+	conf := config.DecompressConfig{TempDir: t.TempDir(), ZipEntry: "*.xml"}
+	tests := map[string]struct {
+		data     []byte
+		expected string
+	}{
+		"plain.txt":   {[]byte("vehicles"), "vehicles"},
+		"data.gz":     {gz.Bytes(), "vehicles"},
+		"data.bz2":    {bzip2Data, "vehicles"},
+		"data.xz":     {xzBuf.Bytes(), "vehicles"},
+		"no-ext":      {gz.Bytes(), "vehicles"},
+		"entries.zip": {zipData(t, "readme.txt", "dir/vehicles.xml"), "vehicles.xml"},
+	}
+	for name, test := range tests {
+		fname := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(fname, test.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		file, err := os.Open(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc, err := decompress(file, conf)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil || string(b) != test.expected {
+			t.Fatalf("Expected %v but got %v (%v) for %s", test.expected, string(b), err, name)
+		}
+	}
+}
+
+func TestDecompressStream(t *testing.T) {
+	conf := config.DecompressConfig{TempDir: t.TempDir()}
+
+	// Zip files that aren't local files are copied to a temporary directory, which is removed on Close.
+	rc, err := decompress(ioutil.NopCloser(bytes.NewReader(zipData(t, "first.xml", "second.xml"))), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(rc)
+	if string(b) != "first.xml" {
+		t.Fatalf("Expected %v but got %v", "first.xml", string(b))
+	}
+	if entries, _ := ioutil.ReadDir(conf.TempDir); len(entries) != 1 {
+		t.Fatalf("Expected %v but got %v", 1, len(entries))
+	}
+	rc.Close()
+	if entries, _ := ioutil.ReadDir(conf.TempDir); len(entries) != 0 {
+		t.Fatalf("Expected %v but got %v", 0, len(entries))
+	}
+
+	// Patterns that don't match any entry are reported.
+	conf.ZipEntry = "*.csv"
+	if _, err = decompress(ioutil.NopCloser(bytes.NewReader(zipData(t, "first.xml"))), conf); err == nil {
+		t.Fatalf("Expected error but got %v", err)
+	}
+	if entries, _ := ioutil.ReadDir(conf.TempDir); len(entries) != 0 {
+		t.Fatalf("Expected %v but got %v", 0, len(entries))
+	}
+}
+
+func TestDecompressZipEntries(t *testing.T) {
+	conf := config.DecompressConfig{TempDir: t.TempDir(), ZipEntry: "*.xml"}
+	rc, err := decompress(ioutil.NopCloser(bytes.NewReader(zipData(t, "first.xml", "readme.txt", "second.xml"))), conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	b, err := ioutil.ReadAll(rc)
+	if err != nil || string(b) != "first.xml\nsecond.xml" {
+		t.Fatalf("Expected %q but got %q (%v)", "first.xml\nsecond.xml", string(b), err)
+	}
+}
@@ -0,0 +1,57 @@
+package dataprovider
+
+import (
+	"io"
+	"time"
+
+	"github.com/mkock/autobot/config"
+	"github.com/mkock/autobot/subprocess"
+)
+
+// ExecProvider is a data provider that runs an external executable, which writes vehicles to stdout as NDJSON. Each
+// run is a new "file", named after the time of the run.
+type ExecProvider struct {
+	config     config.ProviderConfig
+	now        func() time.Time
+	lastSynced string
+}
+
+// NewExecProvider returns a new ExecProvider.
+func NewExecProvider(conf config.ProviderConfig) *ExecProvider {
+	return &ExecProvider{config: conf, now: time.Now}
+}
+
+// Open verifies that the executable exists.
+func (prov *ExecProvider) Open() error {
+	return subprocess.Check(prov.config.ExecConfig)
+}
+
+// Close does nothing.
+func (prov *ExecProvider) Close() error {
+	return nil
+}
+
+// runName returns the name of a new run, which is always newer than previous runs.
+func (prov *ExecProvider) runName() string {
+	return prov.config.FilePrefix + prov.now().UTC().Format(defaultTimeLayout) + ".ndjson"
+}
+
+// CheckForLatest returns the name of a new run, since the executable may always provide new vehicles. The given name
+// is remembered as the last synchronised run.
+func (prov *ExecProvider) CheckForLatest(fname string) (string, error) {
+	prov.lastSynced = fname
+	return prov.runName(), nil
+}
+
+// CheckForNewer returns the name of a new run. The given name is remembered as the last synchronised run.
+func (prov *ExecProvider) CheckForNewer(fname string) ([]string, error) {
+	prov.lastSynced = fname
+	return []string{prov.runName()}, nil
+}
+
+// Provide runs the executable and returns its output. Closing the output waits for the executable to exit, and
+// reports whether it failed. The name of the last synchronised run is passed on to the executable, which may use it to
+// only provide vehicles that have changed since.
+func (prov *ExecProvider) Provide(fname string) (io.ReadCloser, error) {
+	return subprocess.Start(prov.config.ExecConfig, subprocess.ModeProvide, "AUTOBOT_RUN="+fname, "AUTOBOT_LAST_SYNCED="+prov.lastSynced)
+}
@@ -3,20 +3,52 @@ package dataprovider
 import (
 	"fmt"
 	"io"
+	"io/ioutil"
+	"log"
 	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/mkock/autobot/config"
 )
 
-// FileProvider is a data provider that supports local files.
-type FileProvider struct{}
+// watchSettle is how long a new file must be left untouched before it's considered complete, so files that are still
+// being copied into a watched directory aren't synchronised.
+const watchSettle = 10 * time.Second
+
+// Watcher is implemented by data providers that can notify autobot about new files as soon as they arrive.
+type Watcher interface {
+	Watch(quit <-chan struct{}) (<-chan string, error)
+}
+
+// FileProvider is a data provider that supports local files. If the provider is configured with a directory (Dir),
+// files are found in that directory by their names, like they are by FtpProvider. Otherwise, files are given by path.
+type FileProvider struct {
+	config  config.ProviderConfig
+	pattern *filePattern
+	settle  time.Duration
+}
 
 // NewFileProvider returns a new FileProvider.
-func NewFileProvider() *FileProvider {
-	return &FileProvider{}
+func NewFileProvider(conf config.ProviderConfig) *FileProvider {
+	return &FileProvider{config: conf, settle: watchSettle}
 }
 
-// Open does nothing for FileProvider.
+// Open verifies the configured directory, if any.
 func (prov *FileProvider) Open() error {
-	return nil
+	if prov.config.Dir == "" {
+		return nil
+	}
+	finfo, err := os.Stat(prov.config.Dir)
+	if err != nil {
+		return err
+	}
+	if !finfo.IsDir() {
+		return fmt.Errorf("fs provider: %s is not a directory", prov.config.Dir)
+	}
+	prov.pattern, err = newFilePattern(prov.config.FtpConfig)
+	return err
 }
 
 // Close does nothing.
@@ -24,8 +56,31 @@ func (prov *FileProvider) Close() error {
 	return nil
 }
 
-// CheckForLatest simply checks if the file is readable.
+// listFiles returns the names of the files in the configured directory. Directories are skipped.
+func (prov *FileProvider) listFiles() ([]string, error) {
+	files, err := ioutil.ReadDir(prov.config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		if !file.IsDir() {
+			names = append(names, file.Name())
+		}
+	}
+	return names, nil
+}
+
+// CheckForLatest returns the newest file in the configured directory, or the given file if none are newer. Without a
+// directory, it simply checks if the file is readable.
 func (prov *FileProvider) CheckForLatest(fname string) (string, error) {
+	if prov.config.Dir != "" {
+		names, err := prov.listFiles()
+		if err != nil {
+			return "", err
+		}
+		return prov.pattern.newest(names, fname)
+	}
 	finfo, err := os.Stat(fname)
 	if err != nil {
 		return "", err
@@ -36,14 +91,92 @@ func (prov *FileProvider) CheckForLatest(fname string) (string, error) {
 	return fname, nil
 }
 
+// CheckForNewer returns the names of all files in the configured directory that are newer than the one given, from
+// oldest to newest. Without a directory, it returns the given file, if it's readable.
+func (prov *FileProvider) CheckForNewer(fname string) ([]string, error) {
+	if prov.config.Dir != "" {
+		names, err := prov.listFiles()
+		if err != nil {
+			return nil, err
+		}
+		return prov.pattern.newer(names, fname)
+	}
+	if _, err := prov.CheckForLatest(fname); err != nil {
+		return nil, err
+	}
+	return []string{fname}, nil
+}
+
 // Provide makes a local file available to autobot.
 func (prov *FileProvider) Provide(fname string) (rc io.ReadCloser, err error) {
+	if prov.config.Dir != "" {
+		fname = filepath.Join(prov.config.Dir, filepath.Base(fname))
+	}
 	rc, err = os.Open(fname)
 	if err != nil {
 		return nil, err
 	}
-	if isZipped(fname) {
-		return unzip(rc)
+	return decompress(rc, prov.config.DecompressConfig)
+}
+
+// Watch watches the configured directory and sends the name of each new file that matches the file pattern, once it
+// has been left untouched for a while. Watching stops when "quit" is closed.
+func (prov *FileProvider) Watch(quit <-chan struct{}) (<-chan string, error) {
+	if prov.pattern == nil {
+		return nil, fmt.Errorf("fs provider: missing Dir to watch")
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err = watcher.Add(prov.config.Dir); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	files := make(chan string)
+	go prov.watch(watcher, files, quit)
+	return files, nil
+}
+
+// watch collects file events from the watcher and sends the names of the files on "files" once they have settled.
+func (prov *FileProvider) watch(watcher *fsnotify.Watcher, files chan<- string, quit <-chan struct{}) {
+	defer close(files)
+	defer watcher.Close()
+	pending := make(map[string]time.Time) // File name => time of last event.
+	ticker := time.NewTicker(prov.settle / 4)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			name := filepath.Base(event.Name)
+			if event.Op&(fsnotify.Create|fsnotify.Write) == 0 {
+				continue
+			}
+			if _, ok := prov.pattern.parse(name); ok {
+				pending[name] = time.Now()
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Watching %s: %s\n", prov.config.Dir, err)
+		case now := <-ticker.C:
+			for name, last := range pending {
+				if now.Sub(last) < prov.settle {
+					continue
+				}
+				delete(pending, name)
+				select {
+				case files <- name:
+				case <-quit:
+					return
+				}
+			}
+		}
 	}
-	return rc, nil
 }
@@ -0,0 +1,68 @@
+package dataprovider
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mkock/autobot/config"
+)
+
+func TestFileProviderDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Stat-20190101-000000.txt", "Stat-20190110-000000.txt", "readme.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prov := NewFileProvider(config.ProviderConfig{FtpConfig: config.FtpConfig{Dir: dir, FilePrefix: "Stat-"}})
+	if err := prov.Open(); err != nil {
+		t.Fatal(err)
+	}
+	newer, err := prov.CheckForNewer("Stat-20190101-000000.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newer) != 1 || newer[0] != "Stat-20190110-000000.txt" {
+		t.Fatalf("Expected %v but got %v", []string{"Stat-20190110-000000.txt"}, newer)
+	}
+	rc, err := prov.Provide(newer[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(rc)
+	rc.Close()
+	if string(b) != newer[0] {
+		t.Fatalf("Expected %v but got %v", newer[0], string(b))
+	}
+}
+
+func TestFileProviderWatch(t *testing.T) {
+	dir := t.TempDir()
+	prov := NewFileProvider(config.ProviderConfig{FtpConfig: config.FtpConfig{Dir: dir, FilePrefix: "Stat-"}})
+	prov.settle = 50 * time.Millisecond
+	if err := prov.Open(); err != nil {
+		t.Fatal(err)
+	}
+	quit := make(chan struct{})
+	defer close(quit)
+	files, err := prov.Watch(quit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Files that don't match the pattern are ignored.
+	for _, name := range []string{"readme.txt", "Stat-20190110-000000.zip"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case fname := <-files:
+		if fname != "Stat-20190110-000000.zip" {
+			t.Fatalf("Expected %v but got %v", "Stat-20190110-000000.zip", fname)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected %v but got nothing", "Stat-20190110-000000.zip")
+	}
+}
@@ -1,32 +1,95 @@
 package dataprovider
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
-	"path/filepath"
-	"strconv"
-	"strings"
+	"path"
 	"time"
 
 	"github.com/mkock/autobot/config"
 	"github.com/secsy/goftp"
 )
 
-// FtpProvider is a data provider that supports file retrieval via FTP.
+// Defaults for retrying FTP operations that fail due to transient network errors. The delay between attempts is
+// doubled after each attempt, up to ftpMaxBackoff.
+const (
+	ftpAttempts   = 5
+	ftpBackoff    = 5 * time.Second
+	ftpMaxBackoff = 2 * time.Minute
+)
+
+// errSizeMismatch is returned when a downloaded file doesn't have the size reported by the FTP server.
+var errSizeMismatch = errors.New("size of downloaded file does not match the size on the server")
+
+// FtpProvider is a data provider that supports file retrieval via FTP. Operations that fail due to transient network
+// errors are retried with exponential backoff, and interrupted downloads are resumed where they stopped.
 type FtpProvider struct {
-	config config.FtpConfig
-	client *goftp.Client
+	config   config.FtpConfig
+	client   *goftp.Client
+	archive  config.S3Config
+	decomp   config.DecompressConfig
+	pattern  *filePattern
+	cache    *Cache
+	attempts int
+	backoff  time.Duration
+}
+
+// NewFtpProvider returns a new FtpProvider. Files are downloaded to the download cache, or to a temporary directory in the configured TempDir.
+func NewFtpProvider(conf config.FtpConfig, decomp config.DecompressConfig) *FtpProvider {
+	return &FtpProvider{config: conf, decomp: decomp, attempts: ftpAttempts, backoff: ftpBackoff}
+}
+
+// SetCache makes the provider keep downloaded files in the given cache, and reuse them instead of downloading them
+// again.
+func (prov *FtpProvider) SetCache(cache *Cache) {
+	prov.cache = cache
+}
+
+// SetArchive makes the provider upload every downloaded file to the given bucket, so other environments can retrieve
+// the files from there instead of from the FTP server.
+func (prov *FtpProvider) SetArchive(archive config.S3Config) {
+	prov.archive = archive
+}
+
+// retry calls fn until it succeeds, fails with an error that isn't transient, or the maximum number of attempts has
+// been reached. The delay between attempts grows exponentially.
+func (prov *FtpProvider) retry(op string, fn func() error) error {
+	delay := prov.backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !isTransient(err) || attempt >= prov.attempts {
+			return err
+		}
+		log.Printf("%s failed: %s. Retrying in %s (attempt %d of %d)...\n", op, err, delay, attempt+1, prov.attempts)
+		time.Sleep(delay)
+		if delay *= 2; delay > ftpMaxBackoff {
+			delay = ftpMaxBackoff
+		}
+	}
 }
 
-// NewFtpProvider returns a new FtpProvider.
-func NewFtpProvider(conf config.FtpConfig) *FtpProvider {
-	return &FtpProvider{config: conf}
+// isTransient reports whether the given error is likely to be temporary, ie. a network error, an interrupted
+// transfer or a 4xx FTP response.
+func isTransient(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, errSizeMismatch) {
+		return true
+	}
+	var tempErr interface{ Temporary() bool }
+	return errors.As(err, &tempErr) && tempErr.Temporary()
 }
 
 // Open establishes the FTP connection.
 func (prov *FtpProvider) Open() error {
+	pattern, err := newFilePattern(prov.config)
+	if err != nil {
+		return err
+	}
+	prov.pattern = pattern
 	dialConf := goftp.Config{
 		User:               prov.config.User,
 		Password:           prov.config.Password,
@@ -39,92 +102,195 @@ func (prov *FtpProvider) Open() error {
 	} else {
 		host = prov.config.Host
 	}
-	log.Printf("Connecting to %s...\n", host)
-	client, dialErr := goftp.DialConfig(dialConf, host)
-	if dialErr != nil {
-		return dialErr
-	}
-	// Run a MLSD/LIST op to verify the connection, due to a flaw in goftp where it doesn't return an error when the
-	// connection fails. See https://github.com/secsy/goftp/issues/35.
-	if _, err := client.ReadDir(prov.config.Dir); err != nil {
-		return err
-	}
-	prov.client = client
-	return nil
+	return prov.retry("Connecting to "+host, func() error {
+		log.Printf("Connecting to %s...\n", host)
+		client, dialErr := goftp.DialConfig(dialConf, host)
+		if dialErr != nil {
+			return dialErr
+		}
+		// Run a MLSD/LIST op to verify the connection, due to a flaw in goftp where it doesn't return an error when the
+		// connection fails. See https://github.com/secsy/goftp/issues/35.
+		if _, err := client.ReadDir(prov.config.Dir); err != nil {
+			client.Close()
+			return err
+		}
+		prov.client = client
+		return nil
+	})
 }
 
 // Close closes the FTP connection.
 func (prov *FtpProvider) Close() error {
+	if prov.client == nil {
+		return nil
+	}
 	return prov.client.Close()
 }
 
+// listFiles returns the names of the files in the configured directory.
+func (prov *FtpProvider) listFiles() ([]string, error) {
+	var files []os.FileInfo
+	err := prov.retry("Listing "+prov.config.Dir, func() (err error) {
+		files, err = prov.client.ReadDir(prov.config.Dir)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file.Name()
+	}
+	return names, nil
+}
+
 // CheckForLatest checks if there are any new files in the same format as the one given and returns
 // the filename of the latest one if possible. Otherwise, the original filename is returned.
 func (prov *FtpProvider) CheckForLatest(fname string) (string, error) {
-	files, err := prov.client.ReadDir(prov.config.Dir)
+	names, err := prov.listFiles()
 	if err != nil {
 		return "", err
 	}
-	if len(files) == 0 {
-		return "", fmt.Errorf("no such file %s", fname)
-	}
-	newest := fname
-	if newest == "" {
-		// Date/time is far in the past so comparisons will always benefit actual files.
-		newest = fmt.Sprintf("%s20000101-000000.zip", prov.config.FilePrefix)
-	}
-	for _, file := range files {
-		if isNewer(file.Name(), newest) {
-			newest = file.Name()
-		}
+	return prov.pattern.newest(names, fname)
+}
+
+// CheckForNewer returns the names of all files that are newer than the one given, from oldest to newest.
+func (prov *FtpProvider) CheckForNewer(fname string) ([]string, error) {
+	names, err := prov.listFiles()
+	if err != nil {
+		return nil, err
 	}
-	return newest, nil
+	return prov.pattern.newer(names, fname)
 }
 
-// Provide make an FTP file available to autobot by downloading it.
+// Provide make an FTP file available to autobot by downloading it, unless a valid copy is cached. Interrupted
+// downloads are resumed, and the size of the downloaded file is verified against the size reported by the server.
 func (prov *FtpProvider) Provide(fname string) (io.ReadCloser, error) {
-	srcPath := filepath.Join(prov.config.Dir, fname)
-	if _, statErr := prov.client.Stat(srcPath); statErr != nil {
-		return nil, statErr
+	return provide(prov.cache, fname, prov.decomp, func(local string) error {
+		return prov.fetch(fname, local)
+	})
+}
+
+// fetch downloads the file with the given name to "local", and archives it if an archive is configured.
+func (prov *FtpProvider) fetch(fname, local string) error {
+	srcPath := path.Join(prov.config.Dir, fname)
+	var size int64
+	err := prov.retry("Stat "+srcPath, func() error {
+		finfo, err := prov.client.Stat(srcPath)
+		if err == nil {
+			size = finfo.Size()
+		}
+		return err
+	})
+	if err != nil {
+		return err
 	}
-	tmp := fmt.Sprintf("/tmp/%s", fname)
-	w, err := os.Create(tmp)
+	w, err := os.Create(local)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	log.Printf("Downloading %s...\n", fname)
-	if err := prov.client.Retrieve(srcPath, w); err != nil {
-		log.Fatal(err)
+	err = prov.retry("Downloading "+fname, func() error {
+		return prov.download(srcPath, w, size)
+	})
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
 	}
 	if err != nil {
-		return nil, err
+		return err
 	}
-	r, err := os.Open(tmp)
-	if isZipped(fname) {
-		return unzip(r)
+	if prov.archive.Bucket != "" {
+		if err := archiveFile(prov.archive, fname, local); err != nil {
+			log.Printf("Unable to archive %s: %s\n", fname, err)
+		}
 	}
-	return r, nil
+	return nil
 }
 
-// isNewer tests whether the date/time part of file1 is newer than the date/time part of file2.
-// Expected file format: ESStatistikListeModtag-YYYYMMDD-HHMMSS.zip.
-func isNewer(file1, file2 string) bool {
-	if file1 == file2 {
-		return false
-	}
-	parts1 := strings.Split(strings.TrimSuffix(file1, ".zip"), "-")
-	parts2 := strings.Split(strings.TrimSuffix(file2, ".zip"), "-")
-	if len(parts1) != 3 || len(parts2) != 3 {
-		return false // Uncomparable.
-	}
-	if parts1[1] == parts2[1] {
-		// If date parts are identical, then compare time parts.
-		time1, _ := strconv.Atoi(parts1[2])
-		time2, _ := strconv.Atoi(parts2[2])
-		return time1 > time2
-	}
-	// At this point, the date parts are not identical, so we compare them directly.
-	date1, _ := strconv.Atoi(parts1[1])
-	date2, _ := strconv.Atoi(parts2[1])
-	return date1 > date2
+// download retrieves the remainder of the file at "srcPath" into "w", starting at the current size of "w", and
+// verifies that the file has the expected size afterwards.
+func (prov *FtpProvider) download(srcPath string, w *os.File, size int64) error {
+	finfo, err := w.Stat()
+	if err != nil {
+		return err
+	}
+	offset := finfo.Size()
+	if offset > size {
+		offset = 0 // The partial file can't be a prefix of the remote file, so start over.
+	}
+	if offset > 0 {
+		log.Printf("Resuming download of %s at byte %d...\n", srcPath, offset)
+		if err = prov.retrieveFrom(srcPath, w, offset); err == errRestUnsupported {
+			offset = 0
+		}
+	}
+	if offset == 0 {
+		if err = w.Truncate(0); err != nil {
+			return err
+		}
+		if _, err = w.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		err = prov.client.Retrieve(srcPath, w)
+	}
+	if err != nil {
+		return err
+	}
+	if finfo, err = w.Stat(); err != nil {
+		return err
+	}
+	if finfo.Size() != size {
+		return fmt.Errorf("%s: %w (got %d bytes, expected %d)", srcPath, errSizeMismatch, finfo.Size(), size)
+	}
+	return nil
+}
+
+// errRestUnsupported is returned by retrieveFrom if the server doesn't support resuming downloads.
+var errRestUnsupported = errors.New("server does not support REST")
+
+// retrieveFrom retrieves the file at "srcPath", starting at the given offset, and appends it to "w". It uses the REST
+// command on a raw connection, since goftp doesn't support retrieving from an offset.
+func (prov *FtpProvider) retrieveFrom(srcPath string, w *os.File, offset int64) error {
+	if _, err := w.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	raw, err := prov.client.OpenRawConn()
+	if err != nil {
+		return err
+	}
+	defer raw.Close()
+	if code, msg, err := raw.SendCommand("TYPE I"); err != nil {
+		return err
+	} else if code != 200 {
+		return fmt.Errorf("TYPE I: unexpected response: %d %s", code, msg)
+	}
+	getConn, err := raw.PrepareDataConn()
+	if err != nil {
+		return err
+	}
+	if code, _, err := raw.SendCommand("REST %d", offset); err != nil {
+		return err
+	} else if code != 350 {
+		return errRestUnsupported
+	}
+	if code, msg, err := raw.SendCommand("RETR %s", srcPath); err != nil {
+		return err
+	} else if code != 125 && code != 150 {
+		return fmt.Errorf("RETR %s: unexpected response: %d %s", srcPath, code, msg)
+	}
+	conn, err := getConn()
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, conn)
+	conn.Close()
+	if err != nil {
+		return err
+	}
+	if code, msg, err := raw.ReadResponse(); err != nil {
+		return err
+	} else if code != 226 && code != 250 {
+		return fmt.Errorf("RETR %s: unexpected response: %d %s", srcPath, code, msg)
+	}
+	return nil
 }
@@ -0,0 +1,56 @@
+package dataprovider
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err       error
+		transient bool
+	}{
+		{&net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, true},
+		{fmt.Errorf("download: %w", io.ErrUnexpectedEOF), true},
+		{fmt.Errorf("file.zip: %w", errSizeMismatch), true},
+		{errors.New("550 no such file"), false},
+	}
+	for _, test := range tests {
+		if isTransient(test.err) != test.transient {
+			t.Fatalf("Expected %v but got %v for %v", test.transient, !test.transient, test.err)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	prov := &FtpProvider{attempts: 3}
+	calls := 0
+	err := prov.retry("test", func() error {
+		calls++
+		return io.ErrUnexpectedEOF
+	})
+	if err != io.ErrUnexpectedEOF || calls != 3 {
+		t.Fatalf("Expected %v but got %v after %d calls", io.ErrUnexpectedEOF, err, calls)
+	}
+
+	// Permanent errors aren't retried.
+	calls = 0
+	permanent := errors.New("530 login incorrect")
+	if err = prov.retry("test", func() error { calls++; return permanent }); err != permanent || calls != 1 {
+		t.Fatalf("Expected %v but got %v after %d calls", permanent, err, calls)
+	}
+
+	// Operations that succeed eventually return no error.
+	calls = 0
+	if err = prov.retry("test", func() error {
+		if calls++; calls < 2 {
+			return &net.OpError{Op: "dial", Err: errors.New("timeout")}
+		}
+		return nil
+	}); err != nil || calls != 2 {
+		t.Fatalf("Expected %v but got %v after %d calls", nil, err, calls)
+	}
+}
@@ -0,0 +1,290 @@
+package dataprovider
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mkock/autobot/config"
+)
+
+// httpAttempts is the number of times a download is attempted before giving up. Interrupted downloads are resumed
+// from where they stopped, if the server supports it.
+const httpAttempts = 3
+
+// maxIndexSize is the maximum size of a directory listing or index file.
+const maxIndexSize = 10 << 20
+
+// hrefPattern matches the link targets in an HTML directory listing.
+var hrefPattern = regexp.MustCompile(`(?i)href\s*=\s*["']([^"'?#]+)`)
+
+// downloadMeta contains the validators of a downloaded file, which are used for conditional and resumed downloads.
+// It's stored next to the downloaded file.
+type downloadMeta struct {
+	ETag         string
+	LastModified string
+	Complete     bool
+}
+
+// HTTPProvider is a data provider that supports file retrieval via HTTP(S).
+type HTTPProvider struct {
+	config  config.ProviderConfig
+	client  *http.Client
+	pattern *filePattern
+	cache   *Cache
+}
+
+// NewHTTPProvider returns a new HTTPProvider. Files are downloaded to the download cache, or to a temporary directory in the configured TempDir.
+func NewHTTPProvider(conf config.ProviderConfig) *HTTPProvider {
+	return &HTTPProvider{config: conf, client: &http.Client{}, cache: NewCache(conf.Cache, conf.Name)}
+}
+
+// Open verifies the configured URL. The HTTP client connects on demand.
+func (prov *HTTPProvider) Open() error {
+	if prov.config.URL == "" {
+		return errors.New("http provider: missing URL")
+	}
+	u, err := url.Parse(prov.config.URL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("http provider: unsupported URL scheme: %s", u.Scheme)
+	}
+	prov.pattern, err = newFilePattern(prov.config.FtpConfig)
+	return err
+}
+
+// Close does nothing.
+func (prov *HTTPProvider) Close() error {
+	return nil
+}
+
+// newRequest returns a GET request for the given URL, with authentication headers.
+func (prov *HTTPProvider) newRequest(rawURL string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if prov.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+prov.config.Token)
+	} else if prov.config.User != "" {
+		req.SetBasicAuth(prov.config.User, prov.config.Password)
+	}
+	return req, nil
+}
+
+// fileURL returns the URL of the file with the given name. Files are expected to reside in the same directory as the
+// directory listing or index file.
+func (prov *HTTPProvider) fileURL(fname string) string {
+	base, _ := url.Parse(prov.config.URL) // Verified by Open.
+	if !strings.HasSuffix(base.Path, "/") && path.Ext(base.Path) == "" {
+		base.Path += "/"
+	}
+	return base.ResolveReference(&url.URL{Path: fname}).String()
+}
+
+// listFiles returns the names of the files in the directory listing or index file.
+func (prov *HTTPProvider) listFiles() ([]string, error) {
+	req, err := prov.newRequest(prov.config.URL)
+	if err != nil {
+		return nil, err
+	}
+	res, err := prov.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http provider: %s responded with status code %d", prov.config.URL, res.StatusCode)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(res.Body, maxIndexSize))
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(res.Header.Get("Content-Type"), "json") || path.Ext(req.URL.Path) == ".json" {
+		return parseIndex(body)
+	}
+	return parseListing(body), nil
+}
+
+// parseIndex parses a JSON index file, which is either a list of file names or a list of objects with a "name" key.
+func parseIndex(body []byte) ([]string, error) {
+	var names []string
+	if err := json.Unmarshal(body, &names); err == nil {
+		return names, nil
+	}
+	var files []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(body, &files); err != nil {
+		return nil, fmt.Errorf("http provider: invalid index: %s", err)
+	}
+	names = make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	return names, nil
+}
+
+// parseListing extracts the file names from the links in an HTML directory listing. Links to directories are skipped.
+func parseListing(body []byte) []string {
+	var names []string
+	for _, match := range hrefPattern.FindAllSubmatch(body, -1) {
+		href, err := url.PathUnescape(string(match[1]))
+		if err != nil || strings.HasSuffix(href, "/") {
+			continue
+		}
+		names = append(names, path.Base(href))
+	}
+	return names
+}
+
+// CheckForLatest checks if there are any new files in the same format as the one given and returns
+// the filename of the latest one if possible. Otherwise, the original filename is returned.
+func (prov *HTTPProvider) CheckForLatest(fname string) (string, error) {
+	files, err := prov.listFiles()
+	if err != nil {
+		return "", err
+	}
+	return prov.pattern.newest(files, fname)
+}
+
+// CheckForNewer returns the names of all files that are newer than the one given, from oldest to newest.
+func (prov *HTTPProvider) CheckForNewer(fname string) ([]string, error) {
+	files, err := prov.listFiles()
+	if err != nil {
+		return nil, err
+	}
+	return prov.pattern.newer(files, fname)
+}
+
+// Provide makes a file available to autobot by downloading it, unless a valid copy is cached. A previously downloaded
+// copy in the cache is reused if the server reports that it hasn't changed, and interrupted downloads are resumed.
+func (prov *HTTPProvider) Provide(fname string) (io.ReadCloser, error) {
+	return provide(prov.cache, fname, prov.config.DecompressConfig, func(local string) error {
+		meta := readMeta(local)
+		for attempt := 1; ; attempt++ {
+			retry, err := prov.download(fname, local, &meta)
+			if err == nil {
+				return nil
+			}
+			if !retry || attempt == httpAttempts {
+				return err
+			}
+			log.Printf("Download of %s interrupted: %s. Resuming...\n", fname, err)
+		}
+	})
+}
+
+// download downloads the file with the given name to "local", using the validators in "meta" for conditional and
+// resumed downloads. It reports whether a failed download may be retried.
+func (prov *HTTPProvider) download(fname, local string, meta *downloadMeta) (bool, error) {
+	req, err := prov.newRequest(prov.fileURL(fname))
+	if err != nil {
+		return false, err
+	}
+	validator := meta.ETag
+	if validator == "" {
+		validator = meta.LastModified
+	}
+	var offset int64
+	if finfo, err := os.Stat(local); err == nil && validator != "" {
+		if meta.Complete {
+			if meta.ETag != "" {
+				req.Header.Set("If-None-Match", meta.ETag)
+			}
+			if meta.LastModified != "" {
+				req.Header.Set("If-Modified-Since", meta.LastModified)
+			}
+		} else if finfo.Size() > 0 {
+			offset = finfo.Size()
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			req.Header.Set("If-Range", validator)
+		}
+	}
+	res, err := prov.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+	flags := os.O_WRONLY | os.O_CREATE
+	switch res.StatusCode {
+	case http.StatusNotModified:
+		log.Printf("%s has not changed, using local copy\n", fname)
+		return false, nil
+	case http.StatusOK:
+		log.Printf("Downloading %s...\n", fname)
+		flags |= os.O_TRUNC
+		*meta = downloadMeta{ETag: res.Header.Get("ETag"), LastModified: res.Header.Get("Last-Modified")}
+	case http.StatusPartialContent:
+		if !strings.HasPrefix(res.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+			return false, fmt.Errorf("http provider: unexpected content range: %s", res.Header.Get("Content-Range"))
+		}
+		log.Printf("Resuming download of %s at byte %d...\n", fname, offset)
+		flags |= os.O_APPEND
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The local copy is no longer a prefix of the remote file, so start over.
+		*meta = downloadMeta{}
+		return true, fmt.Errorf("http provider: range not satisfiable for %s", fname)
+	default:
+		return false, fmt.Errorf("http provider: %s responded with status code %d", req.URL, res.StatusCode)
+	}
+	meta.Complete = false
+	if err = writeMeta(local, *meta); err != nil {
+		return false, err
+	}
+	file, err := os.OpenFile(local, flags, 0644)
+	if err != nil {
+		return false, err
+	}
+	_, err = io.Copy(file, res.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return true, err
+	}
+	meta.Complete = true
+	return false, writeMeta(local, *meta)
+}
+
+// metaExt is the extension of the files that contain the download metadata of local files.
+const metaExt = ".meta"
+
+// metaFile returns the name of the file containing the download metadata for the given local file.
+func metaFile(local string) string {
+	return local + metaExt
+}
+
+// readMeta returns the download metadata of the given local file. Missing or unreadable metadata results in an empty
+// downloadMeta, which causes the file to be downloaded from scratch.
+func readMeta(local string) downloadMeta {
+	var meta downloadMeta
+	b, err := ioutil.ReadFile(metaFile(local))
+	if err != nil {
+		return meta
+	}
+	if err = json.Unmarshal(b, &meta); err != nil {
+		return downloadMeta{}
+	}
+	return meta
+}
+
+// writeMeta writes the download metadata of the given local file.
+func writeMeta(local string, meta downloadMeta) error {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(metaFile(local), b, 0644)
+}
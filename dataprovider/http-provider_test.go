@@ -0,0 +1,142 @@
+package dataprovider
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mkock/autobot/config"
+)
+
+const testContent = "<ns:Statistik>some vehicle data</ns:Statistik>"
+
+// newTestServer returns a server with a JSON index, an HTML listing and two data files. The first request for the
+// newest data file is interrupted halfway. All requests are recorded.
+func newTestServer(t *testing.T, requests *[]*http.Request) *httptest.Server {
+	interrupted := false
+	modTime := time.Date(2019, 1, 10, 12, 0, 0, 0, time.UTC)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/data/index.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"name": "Stat-20190101-000000.txt"}, {"name": "Stat-20190110-000000.txt"}]`))
+	})
+	mux.HandleFunc("/data/", func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r)
+		if user, pass, ok := r.BasicAuth(); ok && (user != "user" || pass != "secret") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/data/":
+			w.Write([]byte(`<a href="../">Parent</a><a href="sub/">sub</a><a href="Stat-20190101-000000.txt">x</a><a href="/data/Stat-20190110-000000.txt">y</a>`))
+		case "/data/Stat-20190110-000000.txt":
+			w.Header().Set("ETag", `"v1"`)
+			if !interrupted {
+				interrupted = true
+				w.Header().Set("Content-Length", "46")
+				w.Write([]byte(testContent[:20]))
+				return
+			}
+			http.ServeContent(w, r, "", modTime, strings.NewReader(testContent))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestHTTPCheckForLatest(t *testing.T) {
+	var requests []*http.Request
+	srv := newTestServer(t, &requests)
+	defer srv.Close()
+	for _, u := range []string{srv.URL + "/data/", srv.URL + "/data/index.json"} {
+		prov := NewHTTPProvider(config.ProviderConfig{FtpConfig: config.FtpConfig{FilePrefix: "Stat-"}, HTTPConfig: config.HTTPConfig{URL: u}})
+		if err := prov.Open(); err != nil {
+			t.Fatal(err)
+		}
+		latest, err := prov.CheckForLatest("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if latest != "Stat-20190110-000000.txt" {
+			t.Fatalf("Expected %v but got %v", "Stat-20190110-000000.txt", latest)
+		}
+	}
+}
+
+func TestHTTPProvide(t *testing.T) {
+	var requests []*http.Request
+	srv := newTestServer(t, &requests)
+	defer srv.Close()
+	prov := NewHTTPProvider(config.ProviderConfig{
+		FtpConfig:  config.FtpConfig{User: "user", Password: "secret"},
+		HTTPConfig: config.HTTPConfig{URL: srv.URL + "/data"},
+		Name:       "test",
+		Cache:      config.CacheConfig{Dir: t.TempDir()},
+	})
+	fname := "Stat-20190110-000000.txt"
+
+	// The first attempt is interrupted, and the second attempt resumes the download.
+	rc, err := prov.Provide(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(rc)
+	rc.Close()
+	if !bytes.Equal(b, []byte(testContent)) {
+		t.Fatalf("Expected %v but got %v", testContent, string(b))
+	}
+	if len(requests) != 2 {
+		t.Fatalf("Expected %v but got %v", 2, len(requests))
+	}
+	if user, _, _ := requests[0].BasicAuth(); user != "user" {
+		t.Fatalf("Expected %v but got %v", "user", user)
+	}
+	if rng := requests[1].Header.Get("Range"); rng != "bytes=20-" {
+		t.Fatalf("Expected %v but got %v", "bytes=20-", rng)
+	}
+
+	// Without a checksum the cached copy is revalidated, and it hasn't changed, so it's used.
+	if err = os.Remove(prov.cache.path(fname) + checksumExt); err != nil {
+		t.Fatal(err)
+	}
+	if rc, err = prov.Provide(fname); err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+	if etag := requests[2].Header.Get("If-None-Match"); etag != `"v1"` {
+		t.Fatalf("Expected %v but got %v", `"v1"`, etag)
+	}
+
+	// Missing files are not retried.
+	if _, err = prov.Provide("Stat-20190111-000000.txt"); err == nil {
+		t.Fatalf("Expected error but got %v", err)
+	}
+	if len(requests) != 4 {
+		t.Fatalf("Expected %v but got %v", 4, len(requests))
+	}
+}
+
+func TestHTTPBearerAuth(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+	prov := NewHTTPProvider(config.ProviderConfig{HTTPConfig: config.HTTPConfig{URL: srv.URL + "/index.json", Token: "abc"}})
+	if err := prov.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := prov.CheckForLatest(""); err == nil {
+		t.Fatalf("Expected error but got %v", err)
+	}
+	if auth != "Bearer abc" {
+		t.Fatalf("Expected %v but got %v", "Bearer abc", auth)
+	}
+}
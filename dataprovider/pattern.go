@@ -0,0 +1,163 @@
+package dataprovider
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mkock/autobot/config"
+)
+
+// defaultTimeLayout is the layout of the date/time part of file names, ie. ESStatistikListeModtag-20190110-000000.zip.
+const defaultTimeLayout = "20060102-150405"
+
+// filePattern matches the names of data files and extracts the date/time that they are ordered by.
+type filePattern struct {
+	re     *regexp.Regexp
+	layout string
+}
+
+// timedFile is a file name with the date/time extracted from it.
+type timedFile struct {
+	name string
+	time time.Time
+}
+
+// newFilePattern returns the file pattern of the given configuration. FilePattern is either a regular expression with
+// a named group "time", or a glob pattern where "{time}" marks the date/time, ie. "Stat-{time}.zip". The date/time is
+// parsed with TimeLayout. Without a FilePattern, file names are expected to be FilePrefix followed by a date/time in
+// the YYYYMMDD-HHMMSS format and an optional extension.
+func newFilePattern(conf config.FtpConfig) (*filePattern, error) {
+	layout := conf.TimeLayout
+	if layout == "" {
+		layout = defaultTimeLayout
+	}
+	var expr string
+	switch {
+	case conf.FilePattern == "" && conf.FilePrefix == "":
+		expr = `^[^-]*-(?P<time>\d{8}-\d{6})(\.\w+)*$`
+	case conf.FilePattern == "":
+		expr = `^` + regexp.QuoteMeta(conf.FilePrefix) + `(?P<time>\d{8}-\d{6})(\.\w+)*$`
+	case strings.Contains(conf.FilePattern, "(?P<time>"):
+		expr = conf.FilePattern
+	case strings.Contains(conf.FilePattern, "{time}"):
+		expr = globToRegexp(conf.FilePattern)
+	default:
+		return nil, fmt.Errorf("invalid FilePattern %q: missing (?P<time>...) group or {time} placeholder", conf.FilePattern)
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid FilePattern %q: %s", conf.FilePattern, err)
+	}
+	return &filePattern{re: re, layout: layout}, nil
+}
+
+// globToRegexp converts a glob pattern with a "{time}" placeholder into an anchored regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch {
+		case strings.HasPrefix(glob[i:], "{time}"):
+			b.WriteString("(?P<time>.+?)")
+			i += len("{time}") - 1
+		case glob[i] == '*':
+			b.WriteString(".*")
+		case glob[i] == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// String returns the regular expression of the pattern.
+func (p *filePattern) String() string {
+	return p.re.String()
+}
+
+// parse returns the date/time of the file with the given name, and whether the name matches the pattern.
+func (p *filePattern) parse(name string) (time.Time, bool) {
+	match := p.re.FindStringSubmatch(name)
+	if match == nil {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(p.layout, match[p.re.SubexpIndex("time")])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// sorted returns the matching file names from oldest to newest. Names that don't match are skipped, and an error is
+// returned if none of them match.
+func (p *filePattern) sorted(names []string) ([]timedFile, error) {
+	files := make([]timedFile, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if t, ok := p.parse(name); ok && !seen[name] {
+			seen[name] = true
+			files = append(files, timedFile{name, t})
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("none of %d files match the pattern %s", len(names), p)
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].time.Before(files[j].time) })
+	return files, nil
+}
+
+// newest returns the newest of the given file names, or "fname" if none of them are newer.
+func (p *filePattern) newest(names []string, fname string) (string, error) {
+	files, err := p.sorted(names)
+	if err != nil {
+		return "", err
+	}
+	newest := files[len(files)-1]
+	if t, ok := p.parse(fname); ok && !newest.time.After(t) {
+		return fname, nil
+	}
+	return newest.name, nil
+}
+
+// newer returns the given file names that are newer than "fname", from oldest to newest. If "fname" is empty or
+// doesn't match the pattern, only the newest file is returned.
+func (p *filePattern) newer(names []string, fname string) ([]string, error) {
+	files, err := p.sorted(names)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := p.parse(fname)
+	if !ok {
+		return []string{files[len(files)-1].name}, nil
+	}
+	var newer []string
+	for _, file := range files {
+		if file.time.After(t) {
+			newer = append(newer, file.name)
+		}
+	}
+	return newer, nil
+}
+
+// IsOlder reports whether the data file "fname" is older than the data file "than", according to the file pattern of
+// the given provider configuration. It returns an error if either name doesn't match the pattern.
+func IsOlder(conf config.ProviderConfig, fname, than string) (bool, error) {
+	p, err := newFilePattern(conf.FtpConfig)
+	if err != nil {
+		return false, err
+	}
+	t, ok := p.parse(fname)
+	if !ok {
+		return false, fmt.Errorf("%s doesn't match the pattern %s", fname, p)
+	}
+	thanT, ok := p.parse(than)
+	if !ok {
+		return false, fmt.Errorf("%s doesn't match the pattern %s", than, p)
+	}
+	return t.Before(thanT), nil
+}
@@ -0,0 +1,78 @@
+package dataprovider
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mkock/autobot/config"
+)
+
+func TestFilePattern(t *testing.T) {
+	tests := []struct {
+		conf     config.FtpConfig
+		names    []string
+		expected string
+	}{
+		{config.FtpConfig{FilePrefix: "Stat-"}, []string{"Stat-20190110-000000.zip", "Stat-20190110-120000.zip", "Other-20200101-000000.zip"}, "Stat-20190110-120000.zip"},
+		{config.FtpConfig{}, []string{"Stat-20190110-000000.zip", "Other-20200101-000000.zip"}, "Other-20200101-000000.zip"},
+		{config.FtpConfig{FilePattern: "dump_{time}.csv.gz", TimeLayout: "2006-01-02"}, []string{"dump_2019-02-01.csv.gz", "dump_2019-10-01.csv.gz", "dump_latest.csv.gz"}, "dump_2019-10-01.csv.gz"},
+		{config.FtpConfig{FilePattern: `^kjoretoy_(?P<time>\d{6})\.csv$`, TimeLayout: "200601"}, []string{"kjoretoy_201912.csv", "kjoretoy_202001.csv", "kjoretoy_202001.csv.bak"}, "kjoretoy_202001.csv"},
+	}
+	for _, test := range tests {
+		pattern, err := newFilePattern(test.conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if newest, err := pattern.newest(test.names, ""); err != nil || newest != test.expected {
+			t.Fatalf("Expected %v but got %v (%v)", test.expected, newest, err)
+		}
+	}
+
+	// Patterns without a timestamp, and invalid regular expressions, are rejected.
+	for _, fpattern := range []string{"dump_*.csv", "(?P<time>[0-9]+"} {
+		if _, err := newFilePattern(config.FtpConfig{FilePattern: fpattern}); err == nil {
+			t.Fatalf("Expected error but got %v", err)
+		}
+	}
+}
+
+func TestFilePatternNewer(t *testing.T) {
+	pattern, _ := newFilePattern(config.FtpConfig{FilePrefix: "Stat-"})
+	names := []string{"Stat-20190110-000000.zip", "Stat-20190101-000000.zip", "readme.txt", "Stat-20190103-120000.zip", "Stat-20190103-080000.zip"}
+	newer, err := pattern.newer(names, "Stat-20190101-000000.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Stat-20190103-080000.zip", "Stat-20190103-120000.zip", "Stat-20190110-000000.zip"}
+	if strings.Join(newer, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected %v but got %v", expected, newer)
+	}
+
+	// Without a previous file, only the newest file is returned.
+	if newer, _ = pattern.newer(names, ""); len(newer) != 1 || newer[0] != "Stat-20190110-000000.zip" {
+		t.Fatalf("Expected %v but got %v", []string{"Stat-20190110-000000.zip"}, newer)
+	}
+
+	// An up to date file is returned as-is.
+	if newest, _ := pattern.newest(names, "Stat-20190110-000000.zip"); newest != "Stat-20190110-000000.zip" {
+		t.Fatalf("Expected %v but got %v", "Stat-20190110-000000.zip", newest)
+	}
+
+	// It's an error if no file matches the pattern.
+	if _, err = pattern.newer([]string{"readme.txt"}, ""); err == nil || !strings.Contains(err.Error(), "match") {
+		t.Fatalf("Expected error but got %v", err)
+	}
+}
+
+func TestIsOlder(t *testing.T) {
+	conf := config.ProviderConfig{FtpConfig: config.FtpConfig{FilePrefix: "Stat-"}}
+	if older, err := IsOlder(conf, "Stat-20190110-000000.zip", "Stat-20190210-000000.zip"); err != nil || !older {
+		t.Fatalf("Expected %v but got %v (%v)", true, older, err)
+	}
+	if older, err := IsOlder(conf, "Stat-20190210-000000.zip", "Stat-20190110-000000.zip"); err != nil || older {
+		t.Fatalf("Expected %v but got %v (%v)", false, older, err)
+	}
+	if _, err := IsOlder(conf, "latest.zip", "Stat-20190110-000000.zip"); err == nil {
+		t.Fatalf("Expected error for a name that doesn't match but got none")
+	}
+}
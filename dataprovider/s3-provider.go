@@ -0,0 +1,119 @@
+package dataprovider
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/mkock/autobot/config"
+)
+
+// S3Provider is a data provider that supports file retrieval from S3-compatible object storage. Files are objects
+// below the configured prefix, and are identified by their key relative to that prefix.
+type S3Provider struct {
+	config  config.ProviderConfig
+	client  *s3Client
+	pattern *filePattern
+	cache   *Cache
+}
+
+// NewS3Provider returns a new S3Provider. Files are downloaded to the download cache, or to a temporary directory in the configured TempDir.
+func NewS3Provider(conf config.ProviderConfig) *S3Provider {
+	return &S3Provider{config: conf, cache: NewCache(conf.Cache, conf.Name)}
+}
+
+// Open verifies the configuration. Connections are established on demand.
+func (prov *S3Provider) Open() error {
+	switch prov.config.LatestBy {
+	case "", "key", "modified":
+	default:
+		return fmt.Errorf("s3 provider: unsupported LatestBy: %s", prov.config.LatestBy)
+	}
+	client, err := newS3Client(prov.config.S3Config)
+	if err != nil {
+		return err
+	}
+	if prov.pattern, err = newFilePattern(prov.config.FtpConfig); err != nil {
+		return err
+	}
+	prov.client = client
+	return nil
+}
+
+// Close does nothing.
+func (prov *S3Provider) Close() error {
+	return nil
+}
+
+// CheckForLatest checks if there are any new objects in the same format as the one given and returns the name of the
+// latest one if possible. Otherwise, the original name is returned. Depending on the LatestBy setting, the latest
+// object is the one with the newest timestamp in its name, or the one that was modified most recently.
+func (prov *S3Provider) CheckForLatest(fname string) (string, error) {
+	objects, err := prov.client.list(prov.config.Prefix)
+	if err != nil {
+		return "", err
+	}
+	if len(objects) == 0 {
+		return "", fmt.Errorf("no such file %s", fname)
+	}
+	if prov.config.LatestBy == "modified" {
+		sortByModified(objects)
+		return prov.name(objects[len(objects)-1]), nil
+	}
+	return prov.pattern.newest(prov.names(objects), fname)
+}
+
+// CheckForNewer returns the names of all objects that are newer than the one given, from oldest to newest. Depending
+// on the LatestBy setting, objects are ordered by the timestamp in their names or by their modification time.
+func (prov *S3Provider) CheckForNewer(fname string) ([]string, error) {
+	objects, err := prov.client.list(prov.config.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	if prov.config.LatestBy != "modified" {
+		return prov.pattern.newer(prov.names(objects), fname)
+	}
+	sortByModified(objects)
+	for i := len(objects) - 1; i >= 0; i-- {
+		if prov.name(objects[i]) == fname {
+			return prov.names(objects[i+1:]), nil
+		}
+	}
+	if len(objects) == 0 {
+		return nil, nil
+	}
+	// The given object no longer exists (or none was given), so only the latest one is returned.
+	return prov.names(objects[len(objects)-1:]), nil
+}
+
+// name returns the name of the object, relative to the configured prefix.
+func (prov *S3Provider) name(obj s3Object) string {
+	return strings.TrimPrefix(obj.Key, prov.config.Prefix)
+}
+
+// names returns the names of the objects, relative to the configured prefix.
+func (prov *S3Provider) names(objects []s3Object) []string {
+	names := make([]string, len(objects))
+	for i, obj := range objects {
+		names[i] = prov.name(obj)
+	}
+	return names
+}
+
+// sortByModified sorts the objects by modification time, from oldest to newest.
+func sortByModified(objects []s3Object) {
+	sort.SliceStable(objects, func(i, j int) bool { return objects[i].LastModified.Before(objects[j].LastModified) })
+}
+
+// Provide makes an object available to autobot by downloading it, unless a valid copy is cached.
+func (prov *S3Provider) Provide(fname string) (io.ReadCloser, error) {
+	return provide(prov.cache, fname, prov.config.DecompressConfig, func(local string) error {
+		src, err := prov.client.get(prov.config.Prefix + fname)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		return copyToFile(local, src, fname)
+	})
+}
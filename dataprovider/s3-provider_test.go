@@ -0,0 +1,150 @@
+package dataprovider
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mkock/autobot/config"
+)
+
+// fakeS3 is an in-memory stand-in for S3-compatible storage with path-style addressing and a single bucket.
+type fakeS3 struct {
+	mu       sync.Mutex
+	bucket   string
+	objects  map[string]string
+	modified map[string]time.Time
+}
+
+func (s3 *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s3.mu.Lock()
+	defer s3.mu.Unlock()
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=minio/") || r.Header.Get("X-Amz-Date") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/"+s3.bucket+"/")
+	switch {
+	case r.Method == http.MethodGet && key == "":
+		var result s3ListResult
+		for k := range s3.objects {
+			if strings.HasPrefix(k, r.URL.Query().Get("prefix")) {
+				result.Contents = append(result.Contents, s3Object{Key: k, LastModified: s3.modified[k]})
+			}
+		}
+		sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+		xml.NewEncoder(w).Encode(struct {
+			XMLName xml.Name `xml:"ListBucketResult"`
+			s3ListResult
+		}{s3ListResult: result})
+	case r.Method == http.MethodGet:
+		obj, ok := s3.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(obj))
+	case r.Method == http.MethodPut:
+		b, _ := ioutil.ReadAll(r.Body)
+		s3.objects[key] = string(b)
+		s3.modified[key] = time.Now()
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newFakeS3() *fakeS3 {
+	old := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &fakeS3{
+		bucket: "dumps",
+		objects: map[string]string{
+			"dmr/Stat-20190101-000000.txt":   "old",
+			"dmr/Stat-20190110-000000.txt":   "new",
+			"other/Stat-20190120-000000.txt": "other",
+		},
+		modified: map[string]time.Time{
+			"dmr/Stat-20190101-000000.txt":   old.Add(48 * time.Hour),
+			"dmr/Stat-20190110-000000.txt":   old,
+			"other/Stat-20190120-000000.txt": old,
+		},
+	}
+}
+
+func TestS3Provider(t *testing.T) {
+	fake := newFakeS3()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	s3Conf := config.S3Config{Endpoint: srv.URL, Bucket: "dumps", Prefix: "dmr/", AccessKey: "minio", SecretKey: "minio123", PathStyle: true}
+	decompConf := config.DecompressConfig{TempDir: t.TempDir()}
+	prov := NewS3Provider(config.ProviderConfig{FtpConfig: config.FtpConfig{FilePrefix: "Stat-"}, S3Config: s3Conf, DecompressConfig: decompConf})
+	if err := prov.Open(); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := prov.CheckForLatest("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != "Stat-20190110-000000.txt" {
+		t.Fatalf("Expected %v but got %v", "Stat-20190110-000000.txt", latest)
+	}
+	rc, err := prov.Provide(latest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(rc)
+	rc.Close()
+	if string(b) != "new" {
+		t.Fatalf("Expected %v but got %v", "new", string(b))
+	}
+
+	// By modification time, the oldest file name is the latest.
+	prov.config.LatestBy = "modified"
+	if latest, _ = prov.CheckForLatest(""); latest != "Stat-20190101-000000.txt" {
+		t.Fatalf("Expected %v but got %v", "Stat-20190101-000000.txt", latest)
+	}
+
+	// Missed objects are returned in order of modification.
+	if newer, _ := prov.CheckForNewer("Stat-20190110-000000.txt"); len(newer) != 1 || newer[0] != "Stat-20190101-000000.txt" {
+		t.Fatalf("Expected %v but got %v", []string{"Stat-20190101-000000.txt"}, newer)
+	}
+	if newer, _ := prov.CheckForNewer("Stat-20190101-000000.txt"); len(newer) != 0 {
+		t.Fatalf("Expected %v but got %v", 0, len(newer))
+	}
+
+	// Wrong credentials are rejected.
+	prov.client.config.AccessKey = "nobody"
+	if _, err = prov.CheckForLatest(""); err == nil {
+		t.Fatalf("Expected error but got %v", err)
+	}
+}
+
+func TestArchiveFile(t *testing.T) {
+	fake := newFakeS3()
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	local := filepath.Join(t.TempDir(), "Stat-20190111-000000.zip")
+	if err := ioutil.WriteFile(local, []byte("zipped"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	archive := config.S3Config{Endpoint: srv.URL, Bucket: "dumps", Prefix: "archive/", AccessKey: "minio", SecretKey: "minio123", PathStyle: true}
+	if err := archiveFile(archive, "Stat-20190111-000000.zip", local); err != nil {
+		t.Fatal(err)
+	}
+	if obj := fake.objects["archive/Stat-20190111-000000.zip"]; obj != "zipped" {
+		t.Fatalf("Expected %v but got %v", "zipped", obj)
+	}
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	query := canonicalQuery(map[string][]string{"prefix": {"dmr/a b"}, "list-type": {"2"}})
+	if query != "list-type=2&prefix=dmr%2Fa%20b" {
+		t.Fatalf("Expected %v but got %v", "list-type=2&prefix=dmr%2Fa%20b", query)
+	}
+}
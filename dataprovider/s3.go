@@ -0,0 +1,224 @@
+package dataprovider
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mkock/autobot/config"
+)
+
+// s3Object is an object in an S3 bucket, as returned by ListObjectsV2.
+type s3Object struct {
+	Key          string
+	LastModified time.Time
+	Size         int64
+}
+
+// s3ListResult is the response of ListObjectsV2.
+type s3ListResult struct {
+	Contents              []s3Object
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+// s3Client is a minimal client for S3-compatible object storage. It supports listing, downloading and uploading
+// objects, with requests signed using AWS Signature Version 4.
+type s3Client struct {
+	config config.S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// newS3Client returns a new s3Client.
+func newS3Client(conf config.S3Config) (*s3Client, error) {
+	if conf.Endpoint == "" || conf.Bucket == "" {
+		return nil, errors.New("s3: missing Endpoint or Bucket")
+	}
+	if _, err := url.Parse(conf.Endpoint); err != nil {
+		return nil, err
+	}
+	if conf.Region == "" {
+		conf.Region = "us-east-1"
+	}
+	return &s3Client{config: conf, client: &http.Client{}, now: time.Now}, nil
+}
+
+// objectURL returns the URL of the object with the given key. An empty key refers to the bucket itself.
+func (c *s3Client) objectURL(key string, query url.Values) *url.URL {
+	u, _ := url.Parse(c.config.Endpoint) // Verified by newS3Client.
+	if c.config.PathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + c.config.Bucket + "/" + key
+	} else {
+		u.Host = c.config.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+	u.RawQuery = query.Encode()
+	return u
+}
+
+// do signs and performs a request. Responses with other status codes than 200 are turned into errors.
+func (c *s3Client) do(method string, u *url.URL, body io.Reader, size int64) (*http.Response, error) {
+	req, err := http.NewRequest(method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	c.sign(req)
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		res.Body.Close()
+		return nil, fmt.Errorf("s3: %s %s responded with status code %d: %s", method, u.Path, res.StatusCode, msg)
+	}
+	return res, nil
+}
+
+// list returns all objects whose key begins with the given prefix.
+func (c *s3Client) list(prefix string) ([]s3Object, error) {
+	var objects []s3Object
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	for {
+		res, err := c.do(http.MethodGet, c.objectURL("", query), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(res.Body).Decode(&result)
+		res.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, result.Contents...)
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// get returns the contents of the object with the given key.
+func (c *s3Client) get(key string) (io.ReadCloser, error) {
+	res, err := c.do(http.MethodGet, c.objectURL(key, nil), nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// putFile uploads the local file with the given name as the object with the given key.
+func (c *s3Client) putFile(key, fname string) error {
+	file, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	finfo, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	res, err := c.do(http.MethodPut, c.objectURL(key, nil), file, finfo.Size())
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+// sign adds AWS Signature Version 4 headers to the request. The payload is not signed, so request bodies can be
+// streamed.
+func (c *s3Client) sign(req *http.Request) {
+	now := c.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := now.Format("20060102") + "/" + c.config.Region + "/s3/aws4_request"
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonical := strings.Join([]string{
+		req.Method,
+		s3Escape(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		"host:" + req.URL.Host + "\nx-amz-content-sha256:UNSIGNED-PAYLOAD\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.config.SecretKey), now.Format("20060102"))
+	for _, part := range []string{c.config.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.config.AccessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the data, using the given key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery returns the query string in the canonical form required for signing: sorted by key, with all
+// keys and values escaped.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, key := range keys {
+		values := query[key]
+		sort.Strings(values)
+		for _, val := range values {
+			parts = append(parts, s3Escape(key, true)+"="+s3Escape(val, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape escapes a string as required for signing: everything but unreserved characters is percent-encoded.
+// Slashes are kept as-is unless escapeSlash is set.
+func s3Escape(str string, escapeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || strings.IndexByte("-_.~", c) >= 0 || (c == '/' && !escapeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// archiveFile uploads the local file with the given name to the archive bucket, below the configured prefix.
+func archiveFile(archive config.S3Config, fname, local string) error {
+	client, err := newS3Client(archive)
+	if err != nil {
+		return err
+	}
+	log.Printf("Archiving %s...\n", fname)
+	return client.putFile(archive.Prefix+filepath.Base(fname), local)
+}
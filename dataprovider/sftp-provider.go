@@ -0,0 +1,172 @@
+package dataprovider
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/mkock/autobot/config"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SftpProvider is a data provider that supports file retrieval via SFTP.
+type SftpProvider struct {
+	config  config.ProviderConfig
+	conn    *ssh.Client
+	client  *sftp.Client
+	pattern *filePattern
+	cache   *Cache
+}
+
+// NewSftpProvider returns a new SftpProvider. Files are downloaded to the download cache, or to a temporary directory in the configured TempDir.
+func NewSftpProvider(conf config.ProviderConfig) *SftpProvider {
+	return &SftpProvider{config: conf, cache: NewCache(conf.Cache, conf.Name)}
+}
+
+// authMethods returns the configured SSH authentication methods: public key authentication if a key file is
+// configured, and password authentication if a password is configured.
+func (prov *SftpProvider) authMethods() ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+	if prov.config.KeyFile != "" {
+		pem, err := ioutil.ReadFile(prov.config.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		var signer ssh.Signer
+		if prov.config.KeyPassphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(pem, []byte(prov.config.KeyPassphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey(pem)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("sftp provider: unable to parse key file %s: %s", prov.config.KeyFile, err)
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	if prov.config.Password != "" {
+		methods = append(methods, ssh.Password(prov.config.Password))
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("sftp provider: missing KeyFile or Password")
+	}
+	return methods, nil
+}
+
+// hostKeyCallback returns a callback that verifies the server's host key against the configured host key or known
+// hosts file. Connecting to servers with unverified host keys is not supported.
+func (prov *SftpProvider) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	switch {
+	case prov.config.HostKey != "":
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(prov.config.HostKey))
+		if err != nil {
+			return nil, fmt.Errorf("sftp provider: unable to parse host key: %s", err)
+		}
+		return ssh.FixedHostKey(key), nil
+	case prov.config.KnownHostsFile != "":
+		return knownhosts.New(prov.config.KnownHostsFile)
+	default:
+		return nil, errors.New("sftp provider: missing HostKey or KnownHostsFile")
+	}
+}
+
+// Open establishes the SSH connection and starts an SFTP session.
+func (prov *SftpProvider) Open() error {
+	pattern, err := newFilePattern(prov.config.FtpConfig)
+	if err != nil {
+		return err
+	}
+	prov.pattern = pattern
+	auth, err := prov.authMethods()
+	if err != nil {
+		return err
+	}
+	hostKeyCallback, err := prov.hostKeyCallback()
+	if err != nil {
+		return err
+	}
+	port := prov.config.Port
+	if port == 0 {
+		port = 22
+	}
+	host := net.JoinHostPort(prov.config.Host, strconv.Itoa(port))
+	log.Printf("Connecting to %s...\n", host)
+	conn, err := ssh.Dial("tcp", host, &ssh.ClientConfig{
+		User:            prov.config.User,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         30 * time.Second,
+	})
+	if err != nil {
+		return err
+	}
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	prov.conn, prov.client = conn, client
+	return nil
+}
+
+// Close closes the SFTP session and the SSH connection.
+func (prov *SftpProvider) Close() error {
+	if prov.client == nil {
+		return nil
+	}
+	prov.client.Close()
+	return prov.conn.Close()
+}
+
+// listFiles returns the names of the files in the configured directory. Directories are skipped.
+func (prov *SftpProvider) listFiles() ([]string, error) {
+	files, err := prov.client.ReadDir(prov.config.Dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		if !file.IsDir() {
+			names = append(names, file.Name())
+		}
+	}
+	return names, nil
+}
+
+// CheckForLatest checks if there are any new files in the same format as the one given and returns
+// the filename of the latest one if possible. Otherwise, the original filename is returned.
+func (prov *SftpProvider) CheckForLatest(fname string) (string, error) {
+	names, err := prov.listFiles()
+	if err != nil {
+		return "", err
+	}
+	return prov.pattern.newest(names, fname)
+}
+
+// CheckForNewer returns the names of all files that are newer than the one given, from oldest to newest.
+func (prov *SftpProvider) CheckForNewer(fname string) ([]string, error) {
+	names, err := prov.listFiles()
+	if err != nil {
+		return nil, err
+	}
+	return prov.pattern.newer(names, fname)
+}
+
+// Provide makes an SFTP file available to autobot by downloading it, unless a valid copy is cached.
+func (prov *SftpProvider) Provide(fname string) (io.ReadCloser, error) {
+	return provide(prov.cache, fname, prov.config.DecompressConfig, func(local string) error {
+		src, err := prov.client.Open(path.Join(prov.config.Dir, fname))
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		return copyToFile(local, src, fname)
+	})
+}
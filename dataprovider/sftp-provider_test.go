@@ -0,0 +1,164 @@
+package dataprovider
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/mkock/autobot/config"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// newSigner returns a new, random ed25519 SSH signer and its private key in PEM format.
+func newSigner(t *testing.T) (ssh.Signer, []byte) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(key, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, pem.EncodeToMemory(block)
+}
+
+// startSftpServer starts an in-process SSH server with the SFTP subsystem. It accepts the password "secret" and the
+// given client key, and returns the server address.
+func startSftpServer(t *testing.T, hostKey ssh.Signer, clientKey ssh.PublicKey) string {
+	conf := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) != "secret" {
+				return nil, ssh.ErrNoAuth
+			}
+			return nil, nil
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) != string(clientKey.Marshal()) {
+				return nil, ssh.ErrNoAuth
+			}
+			return nil, nil
+		},
+	}
+	conf.AddHostKey(hostKey)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSftp(conn, conf)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// serveSftp handles a single SSH connection, serving SFTP sessions from the local file system.
+func serveSftp(conn net.Conn, conf *ssh.ServerConfig) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, conf)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChan := range chans {
+		if newChan.ChannelType() != "session" {
+			newChan.Reject(ssh.UnknownChannelType, "unsupported channel type")
+			continue
+		}
+		channel, requests, err := newChan.Accept()
+		if err != nil {
+			return
+		}
+		go func(in <-chan *ssh.Request) {
+			for req := range in {
+				req.Reply(req.Type == "subsystem" && string(req.Payload[4:]) == "sftp", nil)
+			}
+		}(requests)
+		server, err := sftp.NewServer(channel)
+		if err != nil {
+			return
+		}
+		go func() {
+			server.Serve()
+			server.Close()
+		}()
+	}
+}
+
+func TestSftpProvider(t *testing.T) {
+	hostKey, _ := newSigner(t)
+	clientKey, clientPEM := newSigner(t)
+	addr := startSftpServer(t, hostKey, clientKey.PublicKey())
+	host, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	dir := t.TempDir()
+	for _, name := range []string{"Stat-20190101-000000.txt", "Stat-20190110-000000.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keyFile := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := ioutil.WriteFile(keyFile, clientPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	trusted := string(ssh.MarshalAuthorizedKey(hostKey.PublicKey()))
+	ftpConf := config.FtpConfig{Host: host, Port: port, User: "autobot", Dir: dir, FilePrefix: "Stat-"}
+
+	// Password and key authentication.
+	for _, sftpConf := range []config.SftpConfig{{HostKey: trusted}, {HostKey: trusted, KeyFile: keyFile}} {
+		conf := config.ProviderConfig{FtpConfig: ftpConf, SftpConfig: sftpConf, DecompressConfig: config.DecompressConfig{TempDir: t.TempDir()}}
+		if sftpConf.KeyFile == "" {
+			conf.Password = "secret"
+		}
+		prov := NewSftpProvider(conf)
+		if err := prov.Open(); err != nil {
+			t.Fatal(err)
+		}
+		latest, err := prov.CheckForLatest("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if latest != "Stat-20190110-000000.txt" {
+			t.Fatalf("Expected %v but got %v", "Stat-20190110-000000.txt", latest)
+		}
+		rc, err := prov.Provide(latest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		if string(b) != latest {
+			t.Fatalf("Expected %v but got %v", latest, string(b))
+		}
+		prov.Close()
+	}
+
+	// Unknown host keys are rejected.
+	otherKey, _ := newSigner(t)
+	conf := config.ProviderConfig{FtpConfig: ftpConf, SftpConfig: config.SftpConfig{HostKey: string(ssh.MarshalAuthorizedKey(otherKey.PublicKey()))}}
+	conf.Password = "secret"
+	if err := NewSftpProvider(conf).Open(); err == nil {
+		t.Fatalf("Expected error but got %v", err)
+	}
+
+	// Host key verification can't be left out.
+	conf.HostKey = ""
+	if err := NewSftpProvider(conf).Open(); err == nil {
+		t.Fatalf("Expected error but got %v", err)
+	}
+}
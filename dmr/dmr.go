@@ -12,33 +12,39 @@ import (
 )
 
 // Service represents DMR (Danish Motor Registry).
-type Service struct{}
+type Service struct {
+	types     map[vehicle.Type]bool
+	catalogue *vehicle.Catalogue
+}
 
-// NewService returns a service that can parse DMR data.
-func NewService() *Service {
-	return &Service{}
+// NewService returns a service that can parse DMR data. Only vehicles of the given types will be imported, and their
+// brand, model and variant names are normalised using the given catalogue.
+func NewService(types []vehicle.Type, cat *vehicle.Catalogue) *Service {
+	service := &Service{types: make(map[vehicle.Type]bool, len(types)), catalogue: cat}
+	for _, t := range types {
+		service.types[t] = true
+	}
+	return service
 }
 
 // processFile takes a file handle to an open XML file, and starts up "numWorkers" workers that will parse each XML
-// excerpt concurrently while delivering the parsed vehicles on the "vehicles" channel. It will send the worker id on
-// the "done" channel for each worker when parsing has completed.
-func (service *Service) processFile(rc io.ReadCloser, numWorkers int, vehicles chan<- vehicle.Vehicle, done chan<- int) {
+// excerpt concurrently while delivering the parsed vehicles on the "vehicles" channel. Each worker sends its id on
+// the "done" channel when parsing has completed. processFile returns once the entire file has been read, with an
+// error if reading failed, ie. because the download was truncated.
+func (service *Service) processFile(rc io.ReadCloser, numWorkers int, vehicles chan<- vehicle.Vehicle, done chan<- int) error {
 	lines := make(chan []string, numWorkers)
 
 	// Start the number of workers (parsers) determined by numWorkers.
 	log.Println("Importing...")
 	for i := 0; i < numWorkers; i++ {
-		go parseExcerpt(i, lines, vehicles, done)
+		go service.parseExcerpt(i, lines, vehicles, done)
 	}
+	defer close(lines)
 
 	// Preparations for the main loop.
 	scanner := bufio.NewScanner(rc)
 	excerpt := make([]string, 0, 200) // Most snippets should fit inside 200 lines.
 	grab := false
-	defer func() {
-		close(lines)
-		rc.Close()
-	}()
 
 	// Main file scanner go routine.
 	for scanner.Scan() {
@@ -55,24 +61,33 @@ func (service *Service) processFile(rc io.ReadCloser, numWorkers int, vehicles c
 			excerpt = append(excerpt, line)
 		}
 	}
+	err := scanner.Err()
+	if closeErr := rc.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
 
-// LoadNew loads all new vehicles from DMR and returns them on a channel.
-// It will send True on channel "done" once all vehicles have been processed.
-func (service *Service) LoadNew(rc io.ReadCloser) (vehicles chan vehicle.Vehicle, done chan bool) {
+// LoadNew loads all new vehicles from DMR and returns them on a channel, which is closed once all vehicles have been
+// delivered. It then sends nil on channel "done" if the entire file was parsed, or the error that stopped it.
+func (service *Service) LoadNew(rc io.ReadCloser) (vehicles chan vehicle.Vehicle, done chan error) {
 	// Nr. of workers = cpu core count - 1 for the main go routine. But at least 2.
 	numWorkers := int(math.Max(2.0, float64(runtime.NumCPU()-1)))
 	bufSize := numWorkers * numWorkers
-	vehicles, done = make(chan vehicle.Vehicle, bufSize), make(chan bool)
+	vehicles, done = make(chan vehicle.Vehicle, bufSize), make(chan error, 1)
 	workerDone := make(chan int, numWorkers)
-	go service.processFile(rc, numWorkers, vehicles, workerDone)
+	readDone := make(chan error, 1)
+	go func() {
+		readDone <- service.processFile(rc, numWorkers, vehicles, workerDone)
+	}()
 
-	// Collect answers from individual workers and send True on "done".
+	// Collect answers from individual workers, then close "vehicles" and report the outcome on "done".
 	go func() {
 		for i := 0; i < numWorkers; i++ {
 			_ = <-workerDone
 		}
-		done <- true
+		close(vehicles)
+		done <- <-readDone
 	}()
 
 	return vehicles, done
@@ -0,0 +1,75 @@
+package dmr
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mkock/autobot/vehicle"
+)
+
+func loadFixture(t *testing.T, types ...vehicle.Type) []vehicle.Vehicle {
+	file, err := os.Open("testdata/statistik.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vehicles, done := NewService(types, nil).LoadNew(file)
+	var list []vehicle.Vehicle
+	for veh := range vehicles {
+		list = append(list, veh)
+	}
+	if err = <-done; err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	return list
+}
+
+func TestLoadNew(t *testing.T) {
+	list := loadFixture(t, vehicle.Car)
+	// The truck is filtered by type.
+	if len(list) != 1 {
+		t.Fatalf("Expected %v but got %v", 1, len(list))
+	}
+	veh := list[0]
+	if veh.MetaData.Ident != 1000000012345678 || veh.RegNr != "AB12345" || veh.VIN != "WVWZZZ1KZCW000001" {
+		t.Fatalf("Expected %v but got %v", "1000000012345678 AB12345 WVWZZZ1KZCW000001", veh)
+	}
+	if veh.Brand != "VW" || veh.Model != "Golf" || veh.Type != vehicle.Car {
+		t.Fatalf("Expected %v but got %v", "VW Golf", veh)
+	}
+}
+
+func TestLoadNewInspection(t *testing.T) {
+	insp := loadFixture(t, vehicle.Car)[0].LastInspection
+	expected := vehicle.Inspection{Date: time.Date(2018, 3, 2, 0, 0, 0, 0, time.UTC), Type: "Periodisk syn", Result: "Godkendt", Odometer: 123}
+	if insp != expected {
+		t.Fatalf("Expected %v but got %v", expected, insp)
+	}
+}
+
+func TestLoadNewTechData(t *testing.T) {
+	tech := loadFixture(t, vehicle.Car)[0].Tech
+	expected := vehicle.TechData{EnginePower: 77, Displacement: 1598, CurbWeight: 1290, TotalWeight: 1840, Seats: 5, Doors: 5, Colour: "Sølv", ModelYear: 2012}
+	if tech != expected {
+		t.Fatalf("Expected %v but got %v", expected, tech)
+	}
+}
+
+func TestCategoryType(t *testing.T) {
+	cases := []struct {
+		stat     vehicleStat
+		expected vehicle.Type
+	}{
+		{vehicleStat{Type: 1, TypeName: "Personbil"}, vehicle.Car},
+		{vehicleStat{Type: 4, TypeName: "Lastbil"}, vehicle.Truck},
+		{vehicleStat{Type: 9, TypeName: "Motorcykel"}, vehicle.Motorcycle}, // The name wins over the number.
+		{vehicleStat{Type: 3}, vehicle.Van},
+		{vehicleStat{Type: 6}, vehicle.Unknown},
+		{vehicleStat{Type: 12, TypeName: "Påhængsredskab"}, vehicle.Unknown},
+	}
+	for _, c := range cases {
+		if actual := categoryType(c.stat); actual != c.expected {
+			t.Fatalf("Expected %v but got %v", c.expected, actual)
+		}
+	}
+}
@@ -3,14 +3,22 @@ package dmr
 import (
 	"encoding/xml"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/mkock/autobot/vehicle"
 )
 
-func typeNrToType(vehType uint64) vehicle.Type {
-	switch vehType {
+// categoryType returns the vehicle type of the given statistic. The category is matched by name (KoeretoejArtNavn),
+// since only category numbers (KoeretoejArtNummer) 1-5 have been confirmed against DMR's data files. The number is
+// only used for statistics without a known name.
+func categoryType(stat vehicleStat) vehicle.Type {
+	if vehType := vehicle.TypeFromDanishName(stat.TypeName); vehType != vehicle.Unknown {
+		return vehType
+	}
+	switch stat.Type {
 	case 1:
 		return vehicle.Car
 	case 2:
@@ -26,31 +34,90 @@ func typeNrToType(vehType uint64) vehicle.Type {
 	}
 }
 
-// parseExcerpt parses XML file using XML decoding.
-func parseExcerpt(id int, lines <-chan []string, parsed chan<- vehicle.Vehicle, done chan<- int) {
+// parseStatusDate parses the date part of a DMR status date. An empty or malformed date results in a zero time.
+func parseStatusDate(str string) time.Time {
+	if len(str) < 10 {
+		return time.Time{}
+	}
+	date, err := time.Parse("2006-01-02", str[:10])
+	if err != nil {
+		return time.Time{}
+	}
+	return date
+}
+
+// parseNumber parses a numeric DMR value, which may contain decimals. Empty or malformed values result in zero.
+func parseNumber(str string) float64 {
+	num, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	if err != nil {
+		return 0
+	}
+	return num
+}
+
+// parseInt parses a numeric DMR value and rounds it to the nearest integer.
+func parseInt(str string) int {
+	return int(math.Round(parseNumber(str)))
+}
+
+// techData extracts the technical data from the vehicle info.
+func techData(info vehicleInfo) vehicle.TechData {
+	return vehicle.TechData{
+		EnginePower:  parseNumber(info.Engine.Power),
+		Displacement: parseInt(info.Engine.Displacement),
+		CurbWeight:   parseInt(info.CurbWeight),
+		TotalWeight:  parseInt(info.TotalWeight),
+		Seats:        parseInt(info.Seats),
+		Doors:        parseInt(info.Doors),
+		Colour:       vehicle.PrettyColour(info.Colour.Type.Name),
+		ModelYear:    parseInt(info.ModelYear),
+	}
+}
+
+// inspection extracts the latest inspection result from the vehicle statistics. Vehicles that have never been
+// inspected will have a zero value Inspection.
+func inspection(stat vehicleStat) vehicle.Inspection {
+	return vehicle.Inspection{
+		Date:     parseStatusDate(stat.Inspection.Date),
+		Type:     stat.Inspection.Type,
+		Result:   stat.Inspection.Result,
+		Odometer: parseInt(stat.Inspection.Odometer),
+	}
+}
+
+// parseExcerpt parses XML file using XML decoding. Only vehicles of the types that the service was configured with
+// are kept.
+func (service *Service) parseExcerpt(id int, lines <-chan []string, parsed chan<- vehicle.Vehicle, done chan<- int) {
 	var proc, keep int // How many excerpts did we process and keep?
 	var stat vehicleStat
 	for excerpt := range lines {
+		stat = vehicleStat{} // Reset, so values from the previous excerpt don't leak into this one.
 		if err := xml.Unmarshal([]byte(strings.Join(excerpt, "\n")), &stat); err != nil {
 			panic(err) // We _could_ skip it, but it's better to halt execution here.
 		}
-		if stat.Type <= 5 {
+		if vehType := categoryType(stat); service.types[vehType] {
 			regDate, err := time.Parse("2006-01-02", stat.Info.FirstRegDate[:10])
 			if err != nil {
 				fmt.Printf("Error: Unable to parse first registration date: %s\n", stat.Info.FirstRegDate)
 				continue
 			}
 			veh := vehicle.Vehicle{
-				MetaData:     vehicle.Meta{Source: stat.Info.Source, Country: vehicle.DK, Ident: stat.Ident, LastUpdated: time.Now(), Disabled: false},
-				Type:         typeNrToType(stat.Type),
-				RegNr:        strings.ToUpper(stat.RegNo),
-				VIN:          strings.ToUpper(stat.Info.VIN),
-				Brand:        vehicle.PrettyBrandName(stat.Info.Designation.BrandTypeName),
-				Model:        stat.Info.Designation.Model.Name, // @TODO Title-case model name? Probably difficult.
-				Variant:      stat.Info.Designation.Variant.Name,
-				FuelType:     vehicle.PrettyFuelType(stat.Info.Engine.Fuel.FuelType),
-				FirstRegDate: regDate,
+				MetaData:       vehicle.Meta{Source: stat.Info.Source, Country: vehicle.DK, Ident: stat.Ident, LastUpdated: time.Now(), Disabled: false},
+				Type:           vehType,
+				RegNr:          strings.ToUpper(stat.RegNo),
+				VIN:            strings.ToUpper(stat.Info.VIN),
+				Brand:          stat.Info.Designation.BrandTypeName,
+				Model:          stat.Info.Designation.Model.Name,
+				Variant:        stat.Info.Designation.Variant.Name,
+				FuelType:       vehicle.PrettyFuelType(stat.Info.Engine.Fuel.FuelType),
+				Fuel:           vehicle.FuelTypeFromString(stat.Info.Engine.Fuel.FuelType),
+				FirstRegDate:   regDate,
+				RegStatus:      vehicle.RegStatusFromString(stat.Info.Status),
+				RegStatusDate:  parseStatusDate(stat.Info.StatusDate),
+				Tech:           techData(stat.Info),
+				LastInspection: inspection(stat),
 			}
+			service.catalogue.Normalise(&veh)
 			if err = veh.GenHash(); err != nil {
 				fmt.Println(err.Error())
 				continue
@@ -1,11 +1,14 @@
 package dmr
 
 // <ns:Statistik>
+// The latest inspection is a sibling of KoeretoejOplysningGrundStruktur, not a part of it, see testdata/statistik.xml.
 type vehicleStat struct {
-	Ident uint64      `xml:"KoeretoejIdent"`
-	Type  uint64      `xml:"KoeretoejArtNummer"`
-	RegNo string      `xml:"RegistreringNummerNummer"`
-	Info  vehicleInfo `xml:"KoeretoejOplysningGrundStruktur"`
+	Ident      uint64            `xml:"KoeretoejIdent"`
+	Type       uint64            `xml:"KoeretoejArtNummer"`
+	TypeName   string            `xml:"KoeretoejArtNavn"`
+	RegNo      string            `xml:"RegistreringNummerNummer"`
+	Info       vehicleInfo       `xml:"KoeretoejOplysningGrundStruktur"`
+	Inspection vehicleInspection `xml:"SynResultatStruktur"`
 }
 
 // <ns:KoeretoejOplysningGrundStruktur>
@@ -16,8 +19,14 @@ type vehicleInfo struct {
 	Variant      string             `xml:"KoeretoejVariantTypeNavn"`
 	VIN          string             `xml:"KoeretoejOplysningStelNummer"`
 	FirstRegDate string             `xml:"KoeretoejOplysningFoersteRegistreringDato"`
+	ModelYear    string             `xml:"KoeretoejOplysningModelAar"`
+	CurbWeight   string             `xml:"KoeretoejOplysningEgenVaegt"`
+	TotalWeight  string             `xml:"KoeretoejOplysningTekniskTotalVaegt"`
+	Seats        string             `xml:"KoeretoejOplysningSiddepladserMaksimum"`
+	Doors        string             `xml:"KoeretoejOplysningAntalDoere"`
 	Engine       vehicleEngine      `xml:"KoeretoejMotorStruktur"`
 	Designation  vehicleDesignation `xml:"KoeretoejBetegnelseStruktur"`
+	Colour       vehicleColour      `xml:"KoeretoejFarveStruktur"`
 }
 
 // <ns:Model>
@@ -40,7 +49,28 @@ type vehicleType struct {
 
 // <ns:KoeretoejMotorStruktur>
 type vehicleEngine struct {
-	Fuel vehicleFuel `xml:"DrivkraftTypeStruktur"`
+	Power        string      `xml:"KoeretoejMotorStoersteEffekt"`
+	Displacement string      `xml:"KoeretoejMotorSlagVolumen"`
+	Fuel         vehicleFuel `xml:"DrivkraftTypeStruktur"`
+}
+
+// <ns:SynResultatStruktur>
+type vehicleInspection struct {
+	Type     string `xml:"SynResultatSynsType"`
+	Result   string `xml:"SynResultatSynsResultat"`
+	Date     string `xml:"SynResultatSynsDato"`
+	Odometer string `xml:"KoeretoejMotorKilometerstand"`
+}
+
+// <ns:KoeretoejFarveStruktur>
+type vehicleColour struct {
+	Type vehicleColourType `xml:"FarveTypeStruktur"`
+}
+
+// <ns:FarveTypeStruktur>
+type vehicleColourType struct {
+	Nr   uint64 `xml:"FarveTypeNummer"`
+	Name string `xml:"FarveTypeNavn"`
 }
 
 // <ns:DrivkraftTypeStruktur>
@@ -0,0 +1,82 @@
+package extlookup
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/mkock/autobot/config"
+	"github.com/mkock/autobot/importer"
+	"github.com/mkock/autobot/subprocess"
+	"github.com/mkock/autobot/vehicle"
+)
+
+// NewExecService returns a new service that performs vehicle lookups in the given country by running an external
+// executable, see the subprocess package for the protocol.
+func NewExecService(name string, country vehicle.RegCountry, cnf config.ExecConfig, cat *vehicle.Catalogue) *ExecService {
+	return &ExecService{name, country, cnf, importer.New(importer.VehicleMapping(name, country.String()), cat)}
+}
+
+// ExecService is an adapter for lookup services that are implemented by external executables.
+type ExecService struct {
+	name     string
+	country  vehicle.RegCountry
+	conf     config.ExecConfig
+	importer *importer.Importer
+}
+
+// execRequest is a lookup request, as written to the executable's stdin.
+type execRequest struct {
+	RegNr string `json:"regnr,omitempty"`
+	VIN   string `json:"vin,omitempty"`
+}
+
+// lookup runs the executable with the given request and converts its response into a vehicle.
+func (service *ExecService) lookup(req execRequest) (vehicle.Vehicle, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return vehicle.Vehicle{}, err
+	}
+	out, err := subprocess.Run(service.conf, subprocess.ModeLookup, append(input, '\n'))
+	if err != nil {
+		return vehicle.Vehicle{}, err
+	}
+	var res struct {
+		Error string `json:"error"`
+	}
+	if err = json.Unmarshal(out, &res); err != nil {
+		return vehicle.Vehicle{}, err
+	}
+	if res.Error != "" {
+		if strings.EqualFold(res.Error, "not found") {
+			return vehicle.Vehicle{}, vehicle.ErrNoSuchVehicle
+		}
+		return vehicle.Vehicle{}, errors.New(res.Error)
+	}
+	var veh vehicle.Vehicle
+	err = service.importer.Read(strings.NewReader(string(out)), importer.FormatNDJSON, func(line int, v vehicle.Vehicle, rowErr error) error {
+		veh = v
+		return rowErr
+	})
+	return veh, err
+}
+
+// LookupRegNr looks up a vehicle based on registration number.
+func (service *ExecService) LookupRegNr(regNr string) (vehicle.Vehicle, error) {
+	return service.lookup(execRequest{RegNr: regNr})
+}
+
+// LookupVIN looks up a vehicle based on VIN number.
+func (service *ExecService) LookupVIN(vin string) (vehicle.Vehicle, error) {
+	return service.lookup(execRequest{VIN: vin})
+}
+
+// Name returns the service name.
+func (service *ExecService) Name() string {
+	return service.name
+}
+
+// Supports returns true if the given country is supported by this service.
+func (service *ExecService) Supports(country vehicle.RegCountry) bool {
+	return country == service.country
+}
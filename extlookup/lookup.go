@@ -1,6 +1,17 @@
 package extlookup
 
-import "github.com/mkock/autobot/vehicle"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mkock/autobot/config"
+	"github.com/mkock/autobot/vehicle"
+)
+
+// ErrNoService is returned for lookups in countries that no lookup service supports.
+var ErrNoService = errors.New("no lookup service supports the country")
 
 // Lookupable is the interface that each direct vehicle lookup service must implement.
 type Lookupable interface {
@@ -10,8 +21,33 @@ type Lookupable interface {
 	LookupRegNr(string) (vehicle.Vehicle, error)
 }
 
-// Manager keeps track of, and lets you interact with, registered lookup services.
-type Manager map[string]Lookupable
+// NewService returns a new lookup service of the configured type for the given country. Supported types are
+// "nrplade" (the default) and "exec".
+func NewService(cnf config.LookupServiceConfig, country vehicle.RegCountry, cat *vehicle.Catalogue) (Lookupable, error) {
+	switch strings.ToLower(cnf.Type) {
+	case "", "nrplade":
+		if country != vehicle.DK {
+			return nil, fmt.Errorf("lookup service type nrplade doesn't support %s", country)
+		}
+		return NewNrpladeService(cnf.Name, cnf.LookupConfig, cat), nil
+	case "exec":
+		return NewExecService(cnf.Name, country, cnf.ExecConfig, cat), nil
+	default:
+		return nil, fmt.Errorf("no such lookup service type: %s", cnf.Type)
+	}
+}
+
+// registration is a lookup service that has been added to a Manager, with its priority.
+type registration struct {
+	service  Lookupable
+	priority int
+}
+
+// Manager keeps track of, and lets you interact with, registered lookup services. Services are tried in order of
+// priority, lowest first, and in the order they were added for equal priorities.
+type Manager struct {
+	services []registration
+}
 
 // NewManager returns a new Manager, which is able to keep track of multiple lookup services.
 func NewManager() *Manager {
@@ -20,24 +56,73 @@ func NewManager() *Manager {
 
 // contains returns true if the Manager contains a lookup service with the given name.
 func (mngr *Manager) contains(name string) bool {
-	_, ok := (*mngr)[name]
-	return ok
+	for _, reg := range mngr.services {
+		if reg.service.Name() == name {
+			return true
+		}
+	}
+	return false
 }
 
-// AddService adds an auto service to the Manager.
-func (mngr *Manager) AddService(service Lookupable) {
+// AddService adds an auto service with the given priority to the Manager. Names must be unique, since they identify
+// the service that answered a lookup, so adding a service with a name that has already been added is an error.
+func (mngr *Manager) AddService(service Lookupable, priority int) error {
 	if mngr.contains(service.Name()) {
-		return
+		return fmt.Errorf("a lookup service named %q has already been added", service.Name())
+	}
+	mngr.services = append(mngr.services, registration{service, priority})
+	sort.SliceStable(mngr.services, func(i, j int) bool {
+		return mngr.services[i].priority < mngr.services[j].priority
+	})
+	return nil
+}
+
+// FindServicesByCountry returns the services that support the given country, in the order they are tried.
+func (mngr *Manager) FindServicesByCountry(country vehicle.RegCountry) []Lookupable {
+	var services []Lookupable
+	for _, reg := range mngr.services {
+		if reg.service.Supports(country) {
+			services = append(services, reg.service)
+		}
 	}
-	(*mngr)[service.Name()] = service
+	return services
+}
+
+// LookupRegNr looks up a vehicle based on registration number, see lookup.
+func (mngr *Manager) LookupRegNr(country vehicle.RegCountry, regNr string) (vehicle.Vehicle, string, error) {
+	return mngr.lookup(country, func(service Lookupable) (vehicle.Vehicle, error) {
+		return service.LookupRegNr(regNr)
+	})
 }
 
-// FindServiceByCountry returns the first service that supports the given country, or nil.
-func (mngr *Manager) FindServiceByCountry(country vehicle.RegCountry) Lookupable {
-	for _, service := range *mngr {
-		if service.Supports(country) {
-			return service
+// LookupVIN looks up a vehicle based on VIN number, see lookup.
+func (mngr *Manager) LookupVIN(country vehicle.RegCountry, vin string) (vehicle.Vehicle, string, error) {
+	return mngr.lookup(country, func(service Lookupable) (vehicle.Vehicle, error) {
+		return service.LookupVIN(vin)
+	})
+}
+
+// lookup tries each service that supports the given country until one of them finds the vehicle, and returns the
+// vehicle and the name of the service that found it. If no service finds the vehicle, the error is
+// vehicle.ErrNoSuchVehicle if all services answered that the vehicle doesn't exist, and otherwise an error that lists
+// the failures.
+func (mngr *Manager) lookup(country vehicle.RegCountry, fn func(Lookupable) (vehicle.Vehicle, error)) (vehicle.Vehicle, string, error) {
+	services := mngr.FindServicesByCountry(country)
+	if len(services) == 0 {
+		return vehicle.Vehicle{}, "", ErrNoService
+	}
+	var failures []string
+	for _, service := range services {
+		veh, err := fn(service)
+		if err == nil {
+			return veh, service.Name(), nil
+		}
+		if err != vehicle.ErrNoSuchVehicle {
+			failures = append(failures, fmt.Sprintf("%s: %s", service.Name(), err))
 		}
 	}
-	return nil
+	if len(failures) > 0 {
+		return vehicle.Vehicle{}, "", fmt.Errorf("lookup failed: %s", strings.Join(failures, "; "))
+	}
+	return vehicle.Vehicle{}, "", vehicle.ErrNoSuchVehicle
 }
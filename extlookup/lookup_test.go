@@ -0,0 +1,105 @@
+package extlookup
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mkock/autobot/vehicle"
+)
+
+// fakeService answers lookups of a single registration number, and fails if err is set.
+type fakeService struct {
+	name  string
+	regNr string
+	err   error
+}
+
+func (service fakeService) Name() string {
+	return service.name
+}
+
+func (service fakeService) Supports(country vehicle.RegCountry) bool {
+	return country == vehicle.DK
+}
+
+func (service fakeService) LookupVIN(vin string) (vehicle.Vehicle, error) {
+	return vehicle.Vehicle{}, vehicle.ErrNoSuchVehicle
+}
+
+func (service fakeService) LookupRegNr(regNr string) (vehicle.Vehicle, error) {
+	if service.err != nil {
+		return vehicle.Vehicle{}, service.err
+	}
+	if regNr != service.regNr {
+		return vehicle.Vehicle{}, vehicle.ErrNoSuchVehicle
+	}
+	return vehicle.Vehicle{RegNr: regNr}, nil
+}
+
+func TestLookupPriority(t *testing.T) {
+	mngr := NewManager()
+	if err := mngr.AddService(fakeService{name: "second", regNr: "AB12345"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	mngr.AddService(fakeService{name: "first", regNr: "AB12345"}, 1)
+	_, name, err := mngr.LookupRegNr(vehicle.DK, "AB12345")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if name != "first" {
+		t.Fatalf("Expected %v but got %v", "first", name)
+	}
+}
+
+func TestLookupFallback(t *testing.T) {
+	mngr := NewManager()
+	if err := mngr.AddService(fakeService{name: "failing", err: errors.New("timeout")}, 0); err != nil {
+		t.Fatal(err)
+	}
+	mngr.AddService(fakeService{name: "missing", regNr: "XY98765"}, 0)
+	if err := mngr.AddService(fakeService{name: "answering", regNr: "AB12345"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	veh, name, err := mngr.LookupRegNr(vehicle.DK, "AB12345")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if name != "answering" || veh.RegNr != "AB12345" {
+		t.Fatalf("Expected %v but got %v (%v)", "answering", name, veh.RegNr)
+	}
+}
+
+func TestLookupErrors(t *testing.T) {
+	mngr := NewManager()
+	if _, _, err := mngr.LookupRegNr(vehicle.DK, "AB12345"); err != ErrNoService {
+		t.Fatalf("Expected %v but got %v", ErrNoService, err)
+	}
+	if err := mngr.AddService(fakeService{name: "missing", regNr: "XY98765"}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mngr.LookupRegNr(vehicle.DK, "AB12345"); err != vehicle.ErrNoSuchVehicle {
+		t.Fatalf("Expected %v but got %v", vehicle.ErrNoSuchVehicle, err)
+	}
+	if _, _, err := mngr.LookupRegNr(vehicle.NO, "AB12345"); err != ErrNoService {
+		t.Fatalf("Expected %v but got %v", ErrNoService, err)
+	}
+	if err := mngr.AddService(fakeService{name: "failing", err: errors.New("timeout")}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := mngr.LookupRegNr(vehicle.DK, "AB12345"); err == nil || err == vehicle.ErrNoSuchVehicle {
+		t.Fatalf("Expected lookup failure but got %v", err)
+	}
+}
+
+func TestLookupDuplicateName(t *testing.T) {
+	mngr := NewManager()
+	if err := mngr.AddService(fakeService{name: "nrplade"}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := mngr.AddService(fakeService{name: "nrplade"}, 2); err == nil {
+		t.Fatalf("Expected error but got %v", err)
+	}
+	if services := mngr.FindServicesByCountry(vehicle.DK); len(services) != 1 {
+		t.Fatalf("Expected %v but got %v", 1, len(services))
+	}
+}
@@ -11,9 +11,9 @@ import (
 	"github.com/mkock/autobot/vehicle"
 )
 
-// NewNrpladeService returns a new Nrplade service for direct vehicle lookups.
-func NewNrpladeService(cnf config.LookupConfig) *NrpladeService {
-	return &NrpladeService{Service{"Nrplade", cnf}}
+// NewNrpladeService returns a new Nrplade service with the given name for direct vehicle lookups.
+func NewNrpladeService(name string, cnf config.LookupConfig, cat *vehicle.Catalogue) *NrpladeService {
+	return &NrpladeService{Service{name, cnf, cat}}
 }
 
 // NrpladeService integrates with a Danish license plate lookup service.
@@ -39,23 +39,6 @@ type nrpladeResponse struct {
 	RegStatus    string `json:"registration_status"`
 }
 
-func decodeType(vehType string) vehicle.Type {
-	switch vehType {
-	case "Personbil":
-		return vehicle.Car
-	case "Stor personbil":
-		return vehicle.Bus
-	case "Varebil":
-		return vehicle.Van
-	case "Lastbil":
-		return vehicle.Truck
-	case "Påhængsvogn":
-		return vehicle.Trailer
-	default:
-		return vehicle.Unknown
-	}
-}
-
 // decodeNrpladeBody does the decoding of the HTTP response into a vehicle.Vehicle.
 func decodeNrpladeBody(body []byte) (vehicle.Vehicle, error) {
 	reader := bytes.NewReader(body)
@@ -71,13 +54,15 @@ func decodeNrpladeBody(body []byte) (vehicle.Vehicle, error) {
 	}
 	vehicle := vehicle.Vehicle{
 		MetaData:     vehicle.Meta{},
-		Type:         decodeType(data.Data.Type),
+		Type:         vehicle.TypeFromDanishName(data.Data.Type),
 		RegNr:        data.Data.Registration,
 		VIN:          data.Data.Vin,
 		Brand:        data.Data.Brand,
 		Model:        data.Data.Model,
 		FuelType:     data.Data.FuelType,
+		Fuel:         vehicle.FuelTypeFromString(data.Data.FuelType),
 		FirstRegDate: regDate,
+		RegStatus:    vehicle.RegStatusFromString(data.Data.RegStatus),
 	}
 	return vehicle, nil
 }
@@ -96,7 +81,7 @@ func (service *NrpladeService) LookupRegNr(regNr string) (vehicle.Vehicle, error
 	if err != nil {
 		return vehicle.Vehicle{}, err
 	}
-	return decodeNrpladeBody(body)
+	return service.normalise(decodeNrpladeBody(body))
 }
 
 // LookupVIN looks up a vehicle based on VIN number.
@@ -113,7 +98,7 @@ func (service *NrpladeService) LookupVIN(vin string) (vehicle.Vehicle, error) {
 	if err != nil {
 		return vehicle.Vehicle{}, err
 	}
-	return decodeNrpladeBody(body)
+	return service.normalise(decodeNrpladeBody(body))
 }
 
 // Name returns the service name.
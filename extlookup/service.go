@@ -6,12 +6,15 @@ import (
 	"net/http"
 
 	"github.com/mkock/autobot/config"
+	"github.com/mkock/autobot/vehicle"
 )
 
 // Service handles the shared part of various Service implementations.
+// Catalogue is used for normalising brand, model and variant names of the vehicles that are looked up.
 type Service struct {
-	Name string
-	Conf config.LookupConfig
+	Name      string
+	Conf      config.LookupConfig
+	Catalogue *vehicle.Catalogue
 }
 
 // Configure takes a LookupConfig (which would probably come from a configuration file).
@@ -35,13 +38,25 @@ func (service *Service) makeReq(reqURL string) ([]byte, error) {
 	if err != nil {
 		return []byte{}, err
 	}
+	defer res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return []byte{}, vehicle.ErrNoSuchVehicle
+	}
 	if res.StatusCode != http.StatusOK {
 		return []byte{}, fmt.Errorf("service responded with status code %d", res.StatusCode)
 	}
-	defer res.Body.Close()
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
 		return []byte{}, err
 	}
 	return body, nil
 }
+
+// normalise normalises the brand, model and variant names of a vehicle that was looked up, unless the lookup failed.
+func (service *Service) normalise(veh vehicle.Vehicle, err error) (vehicle.Vehicle, error) {
+	if err != nil {
+		return veh, err
+	}
+	service.Catalogue.Normalise(&veh)
+	return veh, nil
+}
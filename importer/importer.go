@@ -0,0 +1,275 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkock/autobot/vehicle"
+)
+
+// RowError is returned for rows of an import file that could not be converted into a vehicle.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+// Error returns the error message, including the line number.
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+// RowFunc is called for each row of an import file with the line number of the row, and either the resulting vehicle
+// or a RowError. Returning an error stops the import.
+type RowFunc func(line int, veh vehicle.Vehicle, err error) error
+
+// row contains the values of a single row, by column name.
+type row map[string]string
+
+// Importer converts CSV and NDJSON files with vehicles into vehicles, according to a Mapping.
+type Importer struct {
+	mapping   Mapping
+	catalogue *vehicle.Catalogue
+}
+
+// New returns a new Importer for the given mapping. Brand, model and variant names of the imported vehicles are
+// normalised using the given catalogue.
+func New(mapping Mapping, cat *vehicle.Catalogue) *Importer {
+	return &Importer{mapping: mapping, catalogue: cat}
+}
+
+// ReadFile reads the vehicles from the file with the given name and calls fn for each row.
+func (imp *Importer) ReadFile(fname string, fn RowFunc) error {
+	format, err := imp.mapping.format(fname)
+	if err != nil {
+		return err
+	}
+	file, err := os.Open(fname)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return imp.Read(file, format, fn)
+}
+
+// Read reads the vehicles from "r", which contains data in the given format, and calls fn for each row.
+func (imp *Importer) Read(r io.Reader, format string, fn RowFunc) error {
+	switch format {
+	case FormatCSV:
+		return imp.readCSV(r, fn)
+	case FormatNDJSON:
+		return imp.readNDJSON(r, fn)
+	default:
+		return fmt.Errorf("unsupported file format: %s", format)
+	}
+}
+
+// readCSV reads a CSV file with a header row.
+func (imp *Importer) readCSV(r io.Reader, fn RowFunc) error {
+	cr := csv.NewReader(r)
+	cr.Comma = []rune(imp.mapping.Delimiter)[0]
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("unable to read CSV header: %s", err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff"))
+	}
+	if err = imp.checkHeader(header); err != nil {
+		return err
+	}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// FieldPos panics for rows that failed before their first field was complete, so the line is taken
+			// from the error.
+			parseErr, ok := err.(*csv.ParseError)
+			if !ok {
+				return err
+			}
+			if err = fn(parseErr.StartLine, vehicle.Vehicle{}, RowError{parseErr.StartLine, err}); err != nil {
+				return err
+			}
+			continue
+		}
+		line, _ := cr.FieldPos(0)
+		values := make(row, len(header))
+		for i, name := range header {
+			if i < len(record) {
+				values[name] = record[i]
+			}
+		}
+		if err = imp.emit(line, values, fn); err != nil {
+			return err
+		}
+	}
+}
+
+// checkHeader verifies that every mapped column is present in the CSV header.
+func (imp *Importer) checkHeader(header []string) error {
+	present := make(map[string]bool, len(header))
+	for _, name := range header {
+		present[name] = true
+	}
+	cols := imp.mapping.Columns
+	for _, name := range []string{cols.Ident, cols.Country, cols.Type, cols.RegNr, cols.VIN, cols.Brand, cols.Model, cols.Variant, cols.FuelType, cols.FirstRegDate, cols.RegStatus} {
+		if name != "" && !present[name] {
+			return fmt.Errorf("column %q from the mapping is missing in the CSV header", name)
+		}
+	}
+	return nil
+}
+
+// readNDJSON reads a file with a JSON object per line. Blank lines are skipped.
+func (imp *Importer) readNDJSON(r io.Reader, fn RowFunc) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var obj map[string]interface{}
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err != nil {
+			if err = fn(line, vehicle.Vehicle{}, RowError{line, err}); err != nil {
+				return err
+			}
+			continue
+		}
+		values := make(row, len(obj))
+		for key, val := range obj {
+			if val != nil {
+				values[key] = fmt.Sprint(val)
+			}
+		}
+		if err := imp.emit(line, values, fn); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// emit converts the row into a vehicle and passes it on to fn.
+func (imp *Importer) emit(line int, values row, fn RowFunc) error {
+	veh, err := imp.vehicle(values)
+	if err != nil {
+		return fn(line, veh, RowError{line, err})
+	}
+	return fn(line, veh, nil)
+}
+
+// get returns the trimmed value of the column that the given field is mapped to.
+func (values row) get(column string) string {
+	if column == "" {
+		return ""
+	}
+	return strings.TrimSpace(values[column])
+}
+
+// vehicle converts a row into a normalised vehicle with a hash.
+func (imp *Importer) vehicle(values row) (vehicle.Vehicle, error) {
+	cols := imp.mapping.Columns
+	country, err := imp.country(values.get(cols.Country))
+	if err != nil {
+		return vehicle.Vehicle{}, err
+	}
+	vehType, err := imp.vehicleType(values.get(cols.Type))
+	if err != nil {
+		return vehicle.Vehicle{}, err
+	}
+	regDate, err := imp.date(values.get(cols.FirstRegDate))
+	if err != nil {
+		return vehicle.Vehicle{}, err
+	}
+	var ident uint64
+	if str := values.get(cols.Ident); str != "" {
+		if ident, err = strconv.ParseUint(str, 10, 64); err != nil {
+			return vehicle.Vehicle{}, fmt.Errorf("invalid ident: %s", str)
+		}
+	}
+	fuel := values.get(cols.FuelType)
+	veh := vehicle.Vehicle{
+		MetaData:     vehicle.Meta{Source: imp.mapping.Name, Country: country, Ident: ident, LastUpdated: time.Now(), Disabled: false},
+		Type:         vehType,
+		RegNr:        strings.ToUpper(strings.Replace(values.get(cols.RegNr), " ", "", -1)),
+		VIN:          strings.ToUpper(values.get(cols.VIN)),
+		Brand:        values.get(cols.Brand),
+		Model:        values.get(cols.Model),
+		Variant:      values.get(cols.Variant),
+		FuelType:     vehicle.PrettyFuelType(fuel),
+		Fuel:         vehicle.FuelTypeFromString(fuel),
+		FirstRegDate: regDate,
+		RegStatus:    vehicle.RegStatusFromString(values.get(cols.RegStatus)),
+	}
+	if veh.RegNr == "" && veh.VIN == "" {
+		return vehicle.Vehicle{}, fmt.Errorf("missing registration number and VIN")
+	}
+	if veh.Brand == "" {
+		return vehicle.Vehicle{}, fmt.Errorf("missing brand")
+	}
+	imp.catalogue.Normalise(&veh)
+	if err = veh.GenHash(); err != nil {
+		return vehicle.Vehicle{}, err
+	}
+	return veh, nil
+}
+
+// country returns the country of registration with the given code, or the default country of the mapping.
+func (imp *Importer) country(code string) (vehicle.RegCountry, error) {
+	if code == "" {
+		code = imp.mapping.Country
+	}
+	if code == "" {
+		return vehicle.DK, fmt.Errorf("missing country")
+	}
+	country := vehicle.RegCountryFromString(strings.ToUpper(code))
+	if country.String() != strings.ToUpper(code) {
+		return vehicle.DK, fmt.Errorf("unsupported country: %s", code)
+	}
+	return country, nil
+}
+
+// vehicleType returns the vehicle type for the given raw value. The type mapping is consulted first, then the vehicle
+// type names, and finally the default type of the mapping.
+func (imp *Importer) vehicleType(raw string) (vehicle.Type, error) {
+	if name, ok := imp.mapping.Types[raw]; ok {
+		return vehicle.TypeFromString(name), nil
+	}
+	if vehType := vehicle.TypeFromString(raw); vehType != vehicle.Unknown {
+		return vehType, nil
+	}
+	if imp.mapping.Type != "" {
+		return vehicle.TypeFromString(imp.mapping.Type), nil
+	}
+	if raw != "" {
+		return vehicle.Unknown, fmt.Errorf("unknown vehicle type: %s", raw)
+	}
+	return vehicle.Unknown, nil
+}
+
+// date parses a date using the date layouts of the mapping. An empty string results in a zero time.
+func (imp *Importer) date(str string) (time.Time, error) {
+	if str == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range imp.mapping.DateLayouts {
+		if date, err := time.Parse(layout, str); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date: %s", str)
+}
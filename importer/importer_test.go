@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mkock/autobot/vehicle"
+)
+
+type result struct {
+	line int
+	veh  vehicle.Vehicle
+	err  error
+}
+
+func importFile(t *testing.T, fname string) []result {
+	mapping, err := LoadMapping("testdata/mapping.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []result
+	err = New(mapping, nil).ReadFile(fname, func(line int, veh vehicle.Vehicle, err error) error {
+		results = append(results, result{line, veh, err})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+func TestImportCSV(t *testing.T) {
+	results := importFile(t, "testdata/vehicles.csv")
+	if len(results) != 5 {
+		t.Fatalf("Expected %v but got %v", 5, len(results))
+	}
+	golf := results[0].veh
+	if results[0].err != nil {
+		t.Fatalf("Expected %v but got %v", nil, results[0].err)
+	}
+	if golf.RegNr != "AB12345" || golf.Brand != "Volkswagen" || golf.MetaData.Source != "leasing" {
+		t.Fatalf("Expected %v but got %v", "AB12345 Volkswagen leasing", golf)
+	}
+	if golf.FirstRegDate.Format("2006-01-02") != "2008-06-12" {
+		t.Fatalf("Expected %v but got %v", "2008-06-12", golf.FirstRegDate)
+	}
+	if golf.MetaData.Hash == 0 {
+		t.Fatalf("Expected hash but got %v", golf.MetaData.Hash)
+	}
+	transit := results[1].veh
+	if transit.Type != vehicle.Van || transit.MetaData.Country != vehicle.NO || transit.Fuel != vehicle.Diesel {
+		t.Fatalf("Expected %v but got %v", "Van NO Diesel", transit)
+	}
+	// Missing brand, invalid date and unsupported country.
+	for i, line := range []int{4, 5, 6} {
+		res := results[i+2]
+		if res.line != line {
+			t.Fatalf("Expected %v but got %v", line, res.line)
+		}
+		if _, ok := res.err.(RowError); !ok {
+			t.Fatalf("Expected RowError but got %v", res.err)
+		}
+	}
+}
+
+func TestImportMalformedCSV(t *testing.T) {
+	mapping, err := LoadMapping("testdata/mapping.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []result
+	data := "Plate;Chassis;Make;Model;Fuel;Registered;Kind;Country\n\"AB1;VW\n"
+	err = New(mapping, nil).Read(strings.NewReader(data), FormatCSV, func(line int, veh vehicle.Vehicle, err error) error {
+		results = append(results, result{line, veh, err})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected %v but got %v", 1, len(results))
+	}
+	if _, ok := results[0].err.(RowError); !ok || results[0].line != 2 {
+		t.Fatalf("Expected RowError on line %v but got %v on line %v", 2, results[0].err, results[0].line)
+	}
+}
+
+func TestImportNDJSON(t *testing.T) {
+	results := importFile(t, "testdata/vehicles.ndjson")
+	if len(results) != 3 {
+		t.Fatalf("Expected %v but got %v", 3, len(results))
+	}
+	if results[0].err != nil || results[1].err != nil {
+		t.Fatalf("Expected %v but got %v, %v", nil, results[0].err, results[1].err)
+	}
+	tesla := results[1].veh
+	if results[1].line != 3 || tesla.Type != vehicle.Truck || tesla.Fuel != vehicle.Electric {
+		t.Fatalf("Expected %v but got %v", "line 3 Truck Electric", results[1])
+	}
+	if results[2].err == nil {
+		t.Fatalf("Expected error but got %v", results[2].err)
+	}
+}
+
+func TestMappingFormat(t *testing.T) {
+	mapping := Mapping{}
+	if format, _ := mapping.format("vehicles.jsonl"); format != FormatNDJSON {
+		t.Fatalf("Expected %v but got %v", FormatNDJSON, format)
+	}
+	if _, err := mapping.format("vehicles.xml"); err == nil {
+		t.Fatalf("Expected error but got %v", err)
+	}
+}
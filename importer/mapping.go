@@ -0,0 +1,110 @@
+package importer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/mkock/autobot/vehicle"
+)
+
+// Supported file formats.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// Columns maps Vehicle fields to the names of the columns (CSV) or keys (NDJSON) that contain them. Fields that are
+// left empty are not imported.
+type Columns struct {
+	Ident        string
+	Country      string
+	Type         string
+	RegNr        string
+	VIN          string
+	Brand        string
+	Model        string
+	Variant      string
+	FuelType     string
+	FirstRegDate string
+	RegStatus    string
+}
+
+// Mapping describes how an import file is converted into vehicles.
+// Name is stored as the source of every imported vehicle. Format is either "csv" or "ndjson", and is derived from the
+// file extension if empty. Delimiter is the CSV field delimiter, and defaults to a comma. DateLayouts lists the
+// accepted date formats in Go's reference time layout, and defaults to "2006-01-02". Country and Type are used for
+// rows that don't specify a country or vehicle type, and Types maps raw vehicle type values to vehicle type names.
+type Mapping struct {
+	Name        string
+	Format      string
+	Delimiter   string
+	DateLayouts []string
+	Country     string
+	Type        string
+	Columns     Columns
+	Types       map[string]string
+}
+
+// LoadMapping returns a Mapping loaded from the TOML file with the given name.
+func LoadMapping(fname string) (Mapping, error) {
+	var mapping Mapping
+	if _, err := toml.DecodeFile(fname, &mapping); err != nil {
+		return mapping, err
+	}
+	return mapping, mapping.validate()
+}
+
+// validate checks that the mapping is usable and fills in defaults.
+func (mapping *Mapping) validate() error {
+	if mapping.Name == "" {
+		return fmt.Errorf("mapping: missing Name")
+	}
+	if mapping.Columns.RegNr == "" && mapping.Columns.VIN == "" {
+		return fmt.Errorf("mapping: a RegNr or VIN column is required")
+	}
+	if mapping.Columns.Brand == "" {
+		return fmt.Errorf("mapping: a Brand column is required")
+	}
+	if len(mapping.DateLayouts) == 0 {
+		mapping.DateLayouts = []string{"2006-01-02"}
+	}
+	if mapping.Delimiter == "" {
+		mapping.Delimiter = ","
+	}
+	if len([]rune(mapping.Delimiter)) != 1 {
+		return fmt.Errorf("mapping: Delimiter must be a single character, got %q", mapping.Delimiter)
+	}
+	if mapping.Type != "" && !knownType(mapping.Type) {
+		return fmt.Errorf("mapping: no such vehicle type: %s", mapping.Type)
+	}
+	for raw, name := range mapping.Types {
+		if !knownType(name) {
+			return fmt.Errorf("mapping: no such vehicle type for %q: %s", raw, name)
+		}
+	}
+	return nil
+}
+
+// knownType reports whether the given string is the name of a vehicle type.
+func knownType(name string) bool {
+	return vehicle.TypeFromString(name) != vehicle.Unknown || strings.EqualFold(name, vehicle.Unknown.String())
+}
+
+// format returns the file format of the file with the given name, according to the mapping.
+func (mapping Mapping) format(fname string) (string, error) {
+	format := strings.ToLower(mapping.Format)
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(fname)) {
+		case ".csv", ".txt":
+			format = FormatCSV
+		case ".ndjson", ".jsonl":
+			format = FormatNDJSON
+		}
+	}
+	if format != FormatCSV && format != FormatNDJSON {
+		return "", fmt.Errorf("unsupported file format for %s, set Format to %q or %q", fname, FormatCSV, FormatNDJSON)
+	}
+	return format, nil
+}
@@ -0,0 +1,77 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/mkock/autobot/vehicle"
+)
+
+// VehicleMapping returns a mapping for NDJSON data whose keys are the names of the Vehicle fields, ie. "RegNr" and
+// "FirstRegDate". Vehicles without a country get the given default country.
+func VehicleMapping(name, country string) Mapping {
+	return Mapping{
+		Name:        name,
+		Format:      FormatNDJSON,
+		Delimiter:   ",",
+		DateLayouts: []string{"2006-01-02", "2006-01-02T15:04:05Z07:00"},
+		Country:     country,
+		Columns: Columns{
+			Ident:        "Ident",
+			Country:      "Country",
+			Type:         "Type",
+			RegNr:        "RegNr",
+			VIN:          "VIN",
+			Brand:        "Brand",
+			Model:        "Model",
+			Variant:      "Variant",
+			FuelType:     "FuelType",
+			FirstRegDate: "FirstRegDate",
+			RegStatus:    "RegStatus",
+		},
+	}
+}
+
+// Parser parses data in the format of a mapping into vehicles for synchronisation, like the parsers of the country
+// plugins.
+type Parser struct {
+	importer *Importer
+	format   string
+}
+
+// NewParser returns a new Parser for data in the format of the given mapping.
+func NewParser(mapping Mapping, cat *vehicle.Catalogue) (*Parser, error) {
+	if err := mapping.validate(); err != nil {
+		return nil, err
+	}
+	format, err := mapping.format("")
+	if err != nil {
+		return nil, err
+	}
+	return &Parser{importer: New(mapping, cat), format: format}, nil
+}
+
+// LoadNew reads the vehicles from "rc" and delivers them on the vehicles channel, which is closed once all vehicles
+// have been delivered. Rows that can't be parsed are logged and skipped. Afterwards, nil is sent on the "done" channel
+// if all of "rc" was read, or the error that stopped it. For the exec provider, this includes the executable failing.
+func (parser *Parser) LoadNew(rc io.ReadCloser) (vehicles chan vehicle.Vehicle, done chan error) {
+	vehicles, done = make(chan vehicle.Vehicle), make(chan error, 1)
+	go func() {
+		log.Println("Importing...")
+		err := parser.importer.Read(rc, parser.format, func(line int, veh vehicle.Vehicle, err error) error {
+			if err != nil {
+				fmt.Printf("Error: %s\n", err)
+				return nil
+			}
+			vehicles <- veh
+			return nil
+		})
+		if closeErr := rc.Close(); err == nil {
+			err = closeErr
+		}
+		close(vehicles)
+		done <- err
+	}()
+	return vehicles, done
+}
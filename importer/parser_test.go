@@ -0,0 +1,36 @@
+package importer
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// failingCloser is a reader whose Close fails, like the output of an executable that exits with an error.
+type failingCloser struct {
+	io.Reader
+}
+
+func (failingCloser) Close() error {
+	return errors.New("exit status 1")
+}
+
+func TestParserLoadNewCloseError(t *testing.T) {
+	parser, err := NewParser(VehicleMapping("test", "DK"), nil)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	data := `{"RegNr":"AB12345","VIN":"WVWZZZ1JZXW000001","Type":"Car","Brand":"VW","Model":"Golf","FirstRegDate":"2010-01-01"}` + "\n"
+	vehicles, done := parser.LoadNew(failingCloser{strings.NewReader(data)})
+	count := 0
+	for range vehicles {
+		count++
+	}
+	if count != 1 {
+		t.Fatalf("Expected %v but got %v", 1, count)
+	}
+	if err = <-done; err == nil {
+		t.Fatalf("Expected close error but got none")
+	}
+}
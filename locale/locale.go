@@ -0,0 +1,113 @@
+package locale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mkock/autobot/vehicle"
+)
+
+// Lang represents a language that enumerated vehicle values can be translated into.
+type Lang string
+
+// List of supported languages.
+const (
+	DA Lang = "da"
+	EN Lang = "en"
+	NB Lang = "nb"
+)
+
+// Default is the language used when no supported language was requested.
+const Default = EN
+
+// Parse returns the supported language matching the given language tag, ie. "da", "da-DK" or "nb-NO". Norwegian
+// tags without a written standard ("no") are treated as Bokmål. The second return value is false if the language
+// isn't supported.
+func Parse(tag string) (Lang, bool) {
+	primary := strings.ToLower(strings.TrimSpace(strings.SplitN(tag, "-", 2)[0]))
+	switch primary {
+	case "da":
+		return DA, true
+	case "en":
+		return EN, true
+	case "nb", "no":
+		return NB, true
+	default:
+		return Default, false
+	}
+}
+
+// FromAcceptLanguage returns the preferred supported language from the value of an Accept-Language HTTP header,
+// such as "da-DK,da;q=0.9,en;q=0.8". Languages with q=0 are not acceptable to the client and are skipped. If none of
+// the languages are supported, Default is returned.
+func FromAcceptLanguage(header string) Lang {
+	type candidate struct {
+		lang    Lang
+		quality float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		lang, ok := Parse(fields[0])
+		if !ok {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality <= 0 {
+			continue // The client doesn't accept the language.
+		}
+		candidates = append(candidates, candidate{lang, quality})
+	}
+	if len(candidates) == 0 {
+		return Default
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].quality > candidates[j].quality })
+	return candidates[0].lang
+}
+
+// translate looks up the translation of the given key in the given table. If there's no translation, the key itself
+// is returned, which means that English output contains the enum names.
+func (lang Lang) translate(table, key string) string {
+	if tr, ok := tables[lang][table+"."+key]; ok {
+		return tr
+	}
+	return key
+}
+
+// Type returns the translated name of the vehicle type.
+func (lang Lang) Type(t vehicle.Type) string {
+	return lang.translate("type", t.String())
+}
+
+// Fuel returns the translated name of the fuel type.
+func (lang Lang) Fuel(ft vehicle.FuelType) string {
+	return lang.translate("fuel", ft.String())
+}
+
+// RegStatus returns the translated name of the registration status.
+func (lang Lang) RegStatus(rs vehicle.RegStatus) string {
+	return lang.translate("status", rs.String())
+}
+
+// Label returns the translation of an English output label, ie. "Inspections".
+func (lang Lang) Label(label string) string {
+	return lang.translate("label", label)
+}
+
+// InspectionTerm returns the translation of an inspection type or result, as delivered by a data source, ie.
+// "Godkendt". Terms without a translation are returned as is.
+func (lang Lang) InspectionTerm(term string) string {
+	if tr, ok := inspectionTerms[strings.ToLower(strings.TrimSpace(term))][lang]; ok {
+		return tr
+	}
+	return term
+}
@@ -0,0 +1,69 @@
+package locale
+
+import (
+	"testing"
+
+	"github.com/mkock/autobot/vehicle"
+)
+
+func TestFromAcceptLanguage(t *testing.T) {
+	cases := map[string]Lang{
+		"da-DK,da;q=0.9,en;q=0.8": DA,
+		"en-US,en;q=0.9":          EN,
+		"nn,no;q=0.8,en;q=0.5":    NB,
+		"de-DE,en;q=0.2,da;q=0.7": DA,
+		"da;q=0,nb;q=0.5":         NB,
+		"nb;q=0.0,en;q=0.1":       EN,
+		"fr-FR":                   Default,
+		"":                        Default,
+	}
+	var actual Lang
+	for in, expected := range cases {
+		actual = FromAcceptLanguage(in)
+		if actual != expected {
+			t.Fatalf("Expected %v but got %v for %q", expected, actual, in)
+		}
+	}
+}
+
+func TestInspectionTerm(t *testing.T) {
+	cases := []struct {
+		lang     Lang
+		term     string
+		expected string
+	}{
+		{EN, "Godkendt", "Approved"},
+		{DA, "Godkjent", "Godkendt"},
+		{NB, "PKK", "Periodisk kjøretøykontroll"},
+		{EN, "Periodisk syn", "Periodic inspection"},
+		{EN, "Afvist", "Afvist"},
+	}
+	for _, c := range cases {
+		if actual := c.lang.InspectionTerm(c.term); actual != c.expected {
+			t.Fatalf("Expected %v but got %v", c.expected, actual)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if actual := DA.Type(vehicle.Car); actual != "Personbil" {
+		t.Fatalf("Expected %v but got %v", "Personbil", actual)
+	}
+	if actual := NB.Fuel(vehicle.Petrol); actual != "Bensin" {
+		t.Fatalf("Expected %v but got %v", "Bensin", actual)
+	}
+	if actual := EN.RegStatus(vehicle.Deregistered); actual != "Deregistered" {
+		t.Fatalf("Expected %v but got %v", "Deregistered", actual)
+	}
+	if actual := DA.Label("Latest odometer"); actual != "Seneste kilometerstand" {
+		t.Fatalf("Expected %v but got %v", "Seneste kilometerstand", actual)
+	}
+	// Every enumerated value must have a translation in every language but English.
+	for _, lang := range []Lang{DA, NB} {
+		for vt := vehicle.Unknown; vt <= vehicle.Quadricycle; vt++ {
+			if _, ok := tables[lang]["type."+vt.String()]; !ok {
+				t.Fatalf("Missing %v translation of %v", lang, vt)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package locale
+
+// tables contains the translations of enumerated vehicle values, keyed by "<table>.<enum name>", and of output
+// labels, keyed by "label.<English label>". English is not listed, as the enum names and labels are used as is.
+var tables = map[Lang]map[string]string{
+	DA: {
+		"type.Unknown":       "Ukendt",
+		"type.Car":           "Personbil",
+		"type.Bus":           "Bus",
+		"type.Van":           "Varebil",
+		"type.Truck":         "Lastbil",
+		"type.Trailer":       "Påhængsvogn",
+		"type.Motorcycle":    "Motorcykel",
+		"type.Moped":         "Knallert",
+		"type.Tractor":       "Traktor",
+		"type.MotorisedTool": "Motorredskab",
+		"type.Camper":        "Autocamper",
+		"type.SemiTrailer":   "Sættevogn",
+		"type.Caravan":       "Campingvogn",
+		"type.Quadricycle":   "Quadricykel",
+
+		"fuel.Unknown":      "Ukendt",
+		"fuel.Petrol":       "Benzin",
+		"fuel.Diesel":       "Diesel",
+		"fuel.Electric":     "El",
+		"fuel.Hybrid":       "Hybrid",
+		"fuel.PluginHybrid": "Plug-in hybrid",
+		"fuel.Hydrogen":     "Brint",
+		"fuel.Gas":          "Gas",
+		"fuel.Other":        "Andet",
+
+		"status.Unknown":       "Ukendt",
+		"status.Registered":    "Registreret",
+		"status.Deregistered":  "Afmeldt",
+		"status.PreRegistered": "Forregistreret",
+		"status.Approved":      "Godkendt",
+
+		"label.Inspections":          "Syn",
+		"label.Latest odometer":      "Seneste kilometerstand",
+		"label.No inspections found": "Ingen syn fundet",
+	},
+	NB: {
+		"type.Unknown":       "Ukjent",
+		"type.Car":           "Personbil",
+		"type.Bus":           "Buss",
+		"type.Van":           "Varebil",
+		"type.Truck":         "Lastebil",
+		"type.Trailer":       "Tilhenger",
+		"type.Motorcycle":    "Motorsykkel",
+		"type.Moped":         "Moped",
+		"type.Tractor":       "Traktor",
+		"type.MotorisedTool": "Motorredskap",
+		"type.Camper":        "Bobil",
+		"type.SemiTrailer":   "Semitrailer",
+		"type.Caravan":       "Campingvogn",
+		"type.Quadricycle":   "Firehjuling",
+
+		"fuel.Unknown":      "Ukjent",
+		"fuel.Petrol":       "Bensin",
+		"fuel.Diesel":       "Diesel",
+		"fuel.Electric":     "Elektrisk",
+		"fuel.Hybrid":       "Hybrid",
+		"fuel.PluginHybrid": "Ladbar hybrid",
+		"fuel.Hydrogen":     "Hydrogen",
+		"fuel.Gas":          "Gass",
+		"fuel.Other":        "Annet",
+
+		"status.Unknown":       "Ukjent",
+		"status.Registered":    "Registrert",
+		"status.Deregistered":  "Avregistrert",
+		"status.PreRegistered": "Forhåndsregistrert",
+		"status.Approved":      "Godkjent",
+
+		"label.Inspections":          "Kontroller",
+		"label.Latest odometer":      "Siste kilometerstand",
+		"label.No inspections found": "Ingen kontroller funnet",
+	},
+}
+
+// inspectionTerms contains the translations of inspection types and results, which the data sources deliver as text
+// in their own language, keyed by the lowercased source term.
+var inspectionTerms = map[string]map[Lang]string{
+	"godkendt":          {DA: "Godkendt", EN: "Approved", NB: "Godkjent"},
+	"godkjent":          {DA: "Godkendt", EN: "Approved", NB: "Godkjent"},
+	"ikke godkendt":     {DA: "Ikke godkendt", EN: "Not approved", NB: "Ikke godkjent"},
+	"ikke godkjent":     {DA: "Ikke godkendt", EN: "Not approved", NB: "Ikke godkjent"},
+	"betinget godkendt": {DA: "Betinget godkendt", EN: "Conditionally approved", NB: "Betinget godkjent"},
+	"periodisk syn":     {DA: "Periodisk syn", EN: "Periodic inspection", NB: "Periodisk kjøretøykontroll"},
+	"pkk":               {DA: "Periodisk syn", EN: "Periodic inspection", NB: "Periodisk kjøretøykontroll"},
+	"registreringssyn":  {DA: "Registreringssyn", EN: "Registration inspection", NB: "Registreringskontroll"},
+	"omsyn":             {DA: "Omsyn", EN: "Re-inspection", NB: "Etterkontroll"},
+}
@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/mkock/autobot/country"
 	"github.com/mkock/autobot/dataprovider"
-	"github.com/mkock/autobot/dmr"
 
 	"github.com/gorhill/cronexpr"
 	"github.com/mkock/autobot/config"
@@ -19,17 +21,19 @@ import (
 type SyncScheduler struct {
 	cnf       config.Config
 	store     *vehicle.Store
+	catalogue *vehicle.Catalogue
 	schedExpr *cronexpr.Expression
 	logger    *log.Logger
+	mu        sync.Mutex // Held while synchronising, so scheduled and watched syncs don't overlap.
 }
 
 // New returns a new scheduler that schedules and runs data synchronisation with the vehicle store
 // on fixed intervals defined by the provided configuration. For scheduling, the common cron time expression syntax
 // is used, but in the five-field variant where the fields have the following interpretation:
 // "minute hours day-of-month month day-of-week"
-func New(cnf config.Config, store *vehicle.Store, logWriter io.Writer) *SyncScheduler {
+func New(cnf config.Config, store *vehicle.Store, cat *vehicle.Catalogue, logWriter io.Writer) *SyncScheduler {
 	logger := log.New(logWriter, "", log.Ldate|log.Ltime)
-	return &SyncScheduler{cnf, store, nil, logger}
+	return &SyncScheduler{cnf: cnf, store: store, catalogue: cat, logger: logger}
 }
 
 // parseTimeExpr parses the schedule given in the Config and assigns a parsed (cron-style) time expression
@@ -42,20 +46,73 @@ func (sched *SyncScheduler) parseTimeExpr() error {
 	return nil
 }
 
-// Start starts the scheduler. It will run forever until interrupted.
+// Start starts the scheduler. It will run forever until interrupted. Providers with the Watch setting are also
+// synchronised as soon as new files appear in their directories.
 // It returns a channel that you can send a bool on in order to interrupt the scheduler and shut it down gracefully.
 func (sched *SyncScheduler) Start() (chan<- bool, error) {
 	if err := sched.parseTimeExpr(); err != nil {
 		return nil, err
 	}
 	stop := make(chan bool)
-	go sched.repeatSync(stop)
+	quit := make(chan struct{})
+	sched.watchProviders(quit)
+	go sched.repeatSync(stop, quit)
 	return stop, nil
 }
 
+// watchProviders starts watching the directories of all providers with the Watch setting, until "quit" is closed.
+// Providers that can't be watched are logged and left to the schedule.
+func (sched *SyncScheduler) watchProviders(quit <-chan struct{}) {
+	for name, provCnf := range sched.cnf.Providers {
+		if !provCnf.Watch {
+			continue
+		}
+		files, err := sched.watch(provCnf, quit)
+		if err != nil {
+			sched.logger.Printf("Sync: unable to watch %s: %s\n", name, err)
+			continue
+		}
+		sched.logger.Printf("Sync: watching %s for new files from %s\n", provCnf.Dir, name)
+		go func(name string, provCnf config.ProviderConfig) {
+			for fname := range files {
+				sched.logger.Printf("Sync: new file %s detected for %s\n", fname, name)
+				sched.mu.Lock()
+				if err := sched.syncProvider(name, provCnf); err != nil {
+					sched.logger.Printf("Sync: %s: %s, will retry later\n", name, err)
+				}
+				sched.mu.Unlock()
+			}
+		}(name, provCnf)
+	}
+}
+
+// watch returns a channel that receives the names of new files from the given provider, which must be a local
+// directory.
+func (sched *SyncScheduler) watch(provCnf config.ProviderConfig, quit <-chan struct{}) (<-chan string, error) {
+	ptype, err := dataprovider.ProvTypeFromString(provCnf.Type)
+	if err != nil {
+		return nil, err
+	}
+	plugin, err := country.ForProvider(provCnf)
+	if err != nil {
+		return nil, err
+	}
+	prov := plugin.NewProvider(ptype, provCnf)
+	watcher, ok := prov.(dataprovider.Watcher)
+	if !ok {
+		return nil, fmt.Errorf("the %s provider type does not support Watch", dataprovider.ProvTypeString(ptype))
+	}
+	if err = prov.Open(); err != nil {
+		return nil, err
+	}
+	return watcher.Watch(quit)
+}
+
 // repeatSync listens on channel "stop", and until it receives a stop signal (a boolean value) on this channel,
 // it will keep calculating the next "tick", sleep until that time, run the synchronisation job and repeat.
-func (sched *SyncScheduler) repeatSync(stop <-chan bool) {
+// Channel "quit" is closed when the scheduler stops.
+func (sched *SyncScheduler) repeatSync(stop <-chan bool, quit chan<- struct{}) {
+	defer close(quit)
 	var (
 		now, next time.Time
 		dur       time.Duration
@@ -80,43 +137,89 @@ func (sched *SyncScheduler) repeatSync(stop <-chan bool) {
 	}
 }
 
-// doSync starts the actual data synchronisation.
-// Note: it currently only supports synchronisation with DMR.
+// doSync synchronises the vehicle store with each configured provider, in alphabetical order. A failing provider
+// does not prevent the remaining providers from being synchronised.
 func (sched *SyncScheduler) doSync() error {
-	var (
-		fname, latest string
-		err           error
-	)
-	fname, _ = sched.store.GetLastSynced()
-	prov := dataprovider.NewProvider(dataprovider.FtpProv, sched.cnf.Providers["DMR"])
-	if err = prov.Open(); err != nil {
-		return err
+	sched.mu.Lock()
+	defer sched.mu.Unlock()
+	names := make([]string, 0, len(sched.cnf.Providers))
+	for name := range sched.cnf.Providers {
+		names = append(names, name)
 	}
-	latest, err = prov.CheckForLatest(fname)
+	sort.Strings(names)
+	var failed []string
+	for _, name := range names {
+		if err := sched.syncProvider(name, sched.cnf.Providers[name]); err != nil {
+			sched.logger.Printf("Sync: %s: %s\n", name, err)
+			failed = append(failed, name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("synchronisation failed for %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// syncProvider synchronises the vehicle store with all files from the provider with the given name that are newer than
+// the last synchronised one, in chronological order. Each file is recorded as synchronised once it completes, so a
+// failure doesn't cause earlier files to be synchronised again. Local file providers without a directory are skipped
+// since they have no notion of new files.
+func (sched *SyncScheduler) syncProvider(name string, provCnf config.ProviderConfig) error {
+	ptype, err := dataprovider.ProvTypeFromString(provCnf.Type)
 	if err != nil {
 		return err
 	}
-	if latest == "" || latest == fname {
-		sched.logger.Println("Sync: No new stat file detected")
+	if ptype == dataprovider.FsProv && provCnf.Dir == "" {
 		return nil
 	}
-	sched.logger.Printf("Sync: synchronising %s from DMR...\n", latest)
-	src, err := prov.Provide(latest)
+	plugin, err := country.ForProvider(provCnf)
 	if err != nil {
 		return err
 	}
-	if src == nil {
-		sched.logger.Println("Sync: no stat file detected. Aborting")
-		return nil
+	parser, err := plugin.NewParser(provCnf, sched.catalogue)
+	if err != nil {
+		return err
 	}
-	id := sched.store.NewSyncOp(dataprovider.ProvTypeString(dataprovider.FtpProv))
-
-	dmrService := dmr.NewService()
-	vehicles, done := dmrService.LoadNew(src)
-	if err = sched.store.Sync(id, vehicles, done); err != nil {
+	fname, _ := sched.store.GetLastSynced(name)
+	prov := plugin.NewProvider(ptype, provCnf)
+	if err = prov.Open(); err != nil {
+		return err
+	}
+	defer prov.Close()
+	newer, err := prov.CheckForNewer(fname)
+	if err != nil {
 		return err
 	}
-	sched.logger.Println(sched.store.Status(id))
+	if len(newer) == 0 {
+		sched.logger.Printf("Sync: No new stat file detected for %s\n", name)
+		return nil
+	}
+	for i, latest := range newer {
+		sched.logger.Printf("Sync: synchronising %s (%d of %d) from %s...\n", latest, i+1, len(newer), name)
+		src, err := prov.Provide(latest)
+		if err != nil {
+			return err
+		}
+		if src == nil {
+			sched.logger.Println("Sync: no stat file detected. Aborting")
+			return nil
+		}
+		id := sched.store.NewSyncOp(name, provCnf.DetectDeregistered)
 
-	return sched.store.SetLastSynced(latest)
+		vehicles, done := parser.LoadNew(src)
+		err = sched.store.Sync(id, vehicles, done)
+		// The parser has closed src already, but closing it again reports whether the provider failed, ie. an exec
+		// provider's executable exiting with an error.
+		if closeErr := src.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+		sched.logger.Println(sched.store.Status(id))
+		if err = sched.store.SetLastSynced(name, latest); err != nil {
+			return err
+		}
+	}
+	return nil
 }
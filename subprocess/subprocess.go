@@ -0,0 +1,197 @@
+// Package subprocess runs external executables that act as data providers or lookup services, so sources that need
+// custom scripts or proprietary SDKs don't have to be compiled into autobot.
+//
+// The executable is run with the configured arguments, followed by the mode:
+//
+//   - "provide": the executable writes vehicles to stdout as NDJSON, one JSON object per line, with the keys Ident,
+//     Country, Type, RegNr, VIN, Brand, Model, Variant, FuelType, FirstRegDate (YYYY-MM-DD) and RegStatus. The name
+//     of the run is given in the AUTOBOT_RUN environment variable, and the name of the last synchronised run in
+//     AUTOBOT_LAST_SYNCED, so the executable may only provide vehicles that have changed since. Since the output may
+//     be such a delta, exec providers can't be used to detect deregistered vehicles.
+//   - "lookup": the executable reads a single JSON request from stdin, ie. {"regnr":"AB12345"} or {"vin":"..."}, and
+//     writes the vehicle as a single JSON object with the keys above to stdout. Unknown vehicles are reported with
+//     {"error":"not found"}.
+//
+// A non-zero exit status is an error, and so is exceeding the timeout. Either way, a synchronisation with the
+// executable's output fails, and the run isn't recorded as synchronised. Anything written to stderr is logged, and
+// included in errors.
+package subprocess
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mkock/autobot/config"
+)
+
+// Modes that executables are run in.
+const (
+	ModeProvide = "provide"
+	ModeLookup  = "lookup"
+)
+
+// Default timeouts for executables that don't configure their own.
+const (
+	defaultProvideTimeout = time.Hour
+	defaultLookupTimeout  = 10 * time.Second
+)
+
+// maxStderr is the number of bytes from the end of stderr that are included in errors.
+const maxStderr = 4096
+
+// stderrBuffer logs each line written to stderr and keeps the last maxStderr bytes.
+type stderrBuffer struct {
+	mu     sync.Mutex
+	prefix string
+	tail   []byte
+	line   []byte
+}
+
+// Write logs complete lines and keeps the tail of the output.
+func (b *stderrBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tail = append(b.tail, p...)
+	if len(b.tail) > maxStderr {
+		b.tail = b.tail[len(b.tail)-maxStderr:]
+	}
+	b.line = append(b.line, p...)
+	for {
+		i := bytes.IndexByte(b.line, '\n')
+		if i < 0 {
+			break
+		}
+		log.Printf("%s: %s\n", b.prefix, bytes.TrimSpace(b.line[:i]))
+		b.line = b.line[i+1:]
+	}
+	return len(p), nil
+}
+
+// String returns the tail of the output.
+func (b *stderrBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.TrimSpace(string(b.tail))
+}
+
+// timeout returns the configured timeout of the mode, or its default.
+func timeout(conf config.ExecConfig, mode string) time.Duration {
+	switch {
+	case mode == ModeLookup && conf.ExecLookupTimeout > 0:
+		return time.Duration(conf.ExecLookupTimeout) * time.Second
+	case mode == ModeLookup:
+		return defaultLookupTimeout
+	case conf.ExecTimeout > 0:
+		return time.Duration(conf.ExecTimeout) * time.Second
+	default:
+		return defaultProvideTimeout
+	}
+}
+
+// command returns the command for running the configured executable in the given mode, and the buffer that stderr is
+// written to. The command is killed when the context is cancelled.
+func command(ctx context.Context, conf config.ExecConfig, mode string, env []string) (*exec.Cmd, *stderrBuffer) {
+	cmd := exec.CommandContext(ctx, conf.ExecCommand, append(append([]string{}, conf.ExecArgs...), mode)...)
+	cmd.Env = append(os.Environ(), env...)
+	stderr := &stderrBuffer{prefix: fmt.Sprintf("%s %s", conf.ExecCommand, mode)}
+	cmd.Stderr = stderr
+	return cmd, stderr
+}
+
+// wrapErr adds the command and the tail of its stderr to an error from running it.
+func wrapErr(ctx context.Context, conf config.ExecConfig, mode string, stderr *stderrBuffer, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("timed out after %s", timeout(conf, mode))
+	}
+	if tail := stderr.String(); tail != "" {
+		return fmt.Errorf("%s %s: %s: %s", conf.ExecCommand, mode, err, tail)
+	}
+	return fmt.Errorf("%s %s: %s", conf.ExecCommand, mode, err)
+}
+
+// Check verifies that the configured executable exists.
+func Check(conf config.ExecConfig) error {
+	if conf.ExecCommand == "" {
+		return fmt.Errorf("subprocess: missing ExecCommand")
+	}
+	_, err := exec.LookPath(conf.ExecCommand)
+	return err
+}
+
+// Run runs the executable in the given mode with the given input on stdin, and returns its output.
+func Run(conf config.ExecConfig, mode string, input []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout(conf, mode))
+	defer cancel()
+	cmd, stderr := command(ctx, conf, mode, nil)
+	cmd.Stdin = bytes.NewReader(input)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, wrapErr(ctx, conf, mode, stderr, err)
+	}
+	return out, nil
+}
+
+// output is the stdout of a running executable. Closing it waits for the executable to exit.
+type output struct {
+	conf   config.ExecConfig
+	mode   string
+	ctx    context.Context
+	cancel context.CancelFunc
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr *stderrBuffer
+	eof    bool
+	once   sync.Once
+	err    error
+}
+
+// Read reads from stdout, and keeps track of whether all of it has been read.
+func (out *output) Read(p []byte) (int, error) {
+	n, err := out.stdout.Read(p)
+	if err == io.EOF {
+		out.eof = true
+	}
+	return n, err
+}
+
+// Close stops reading the output and waits for the executable to exit. If the output hasn't been read completely,
+// ie. because a sync was aborted, the executable is killed instead of being left to finish. It returns an error if
+// the executable failed, timed out or was killed. Closing the output again returns the same error.
+func (out *output) Close() error {
+	out.once.Do(func() {
+		defer out.cancel()
+		if !out.eof {
+			out.cancel()
+		}
+		if err := out.cmd.Wait(); err != nil {
+			out.err = wrapErr(out.ctx, out.conf, out.mode, out.stderr, err)
+		}
+	})
+	return out.err
+}
+
+// Start starts the executable in the given mode with the given environment variables, ie. "KEY=value", and returns
+// its stdout. Closing the returned reader waits for the executable to exit, or kills it if stdout hasn't been read
+// completely, and reports whether it failed.
+func Start(conf config.ExecConfig, mode string, env ...string) (io.ReadCloser, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout(conf, mode))
+	cmd, stderr := command(ctx, conf, mode, env)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		cancel()
+		return nil, wrapErr(ctx, conf, mode, stderr, err)
+	}
+	return &output{conf: conf, mode: mode, ctx: ctx, cancel: cancel, cmd: cmd, stdout: stdout, stderr: stderr}, nil
+}
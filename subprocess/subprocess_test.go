@@ -0,0 +1,92 @@
+package subprocess
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mkock/autobot/config"
+)
+
+// script returns a configuration that runs the given shell script. The mode is available to the script as $1.
+func script(src string) config.ExecConfig {
+	return config.ExecConfig{ExecCommand: "sh", ExecArgs: []string{"-c", src, "sh"}}
+}
+
+func TestRun(t *testing.T) {
+	out, err := Run(script(`test "$1" = lookup && cat`), ModeLookup, []byte(`{"regnr":"AB12345"}`))
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if string(out) != `{"regnr":"AB12345"}` {
+		t.Fatalf("Expected %v but got %v", `{"regnr":"AB12345"}`, string(out))
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	_, err := Run(script(`echo "no credentials" >&2; exit 3`), ModeLookup, nil)
+	if err == nil || !strings.Contains(err.Error(), "no credentials") {
+		t.Fatalf("Expected error with stderr but got %v", err)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	conf := script(`exec sleep 5`)
+	conf.ExecLookupTimeout = 1
+	_, err := Run(conf, ModeLookup, nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("Expected timeout error but got %v", err)
+	}
+}
+
+func TestStart(t *testing.T) {
+	rc, err := Start(script(`echo "$1 $AUTOBOT_RUN"`), ModeProvide, "AUTOBOT_RUN=run1")
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	out, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if string(out) != "provide run1\n" {
+		t.Fatalf("Expected %q but got %q", "provide run1\n", string(out))
+	}
+	if err = rc.Close(); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+}
+
+func TestStartFailure(t *testing.T) {
+	rc, err := Start(script(`echo partial; exit 1`), ModeProvide)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if _, err = ioutil.ReadAll(rc); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if err = rc.Close(); err == nil || !strings.Contains(err.Error(), "exit status 1") {
+		t.Fatalf("Expected exit status error but got %v", err)
+	}
+	if err2 := rc.Close(); err2 != err {
+		t.Fatalf("Expected %v but got %v", err, err2)
+	}
+}
+
+func TestStartEarlyClose(t *testing.T) {
+	rc, err := Start(script(`while :; do echo vehicle; done`), ModeProvide)
+	if err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	if _, err = rc.Read(make([]byte, 8)); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	// The executable never finishes on its own, so it must be killed rather than drained.
+	start := time.Now()
+	if err = rc.Close(); err == nil {
+		t.Fatalf("Expected error on close but got none")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected %v but got %v", "close within 5s", elapsed)
+	}
+}
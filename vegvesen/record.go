@@ -0,0 +1,177 @@
+package vegvesen
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mkock/autobot/vehicle"
+)
+
+// Names of the CSV columns in the bulk data from Statens vegvesen. Columns may appear in any order, and only the
+// columns for registration number, VIN, technical vehicle group, brand and first registration date are mandatory.
+const (
+	colIdent         = "kjoretoy_id"
+	colRegNr         = "kjennemerke"
+	colVIN           = "understellsnummer"
+	colGroup         = "tekn_kjtgr"
+	colBrand         = "merke"
+	colModel         = "handelsbetegnelse"
+	colVariant       = "typebetegnelse"
+	colFuel          = "drivstoff"
+	colFirstRegDate  = "forstegangsregistrert"
+	colStatus        = "registreringsstatus"
+	colStatusDate    = "registreringsstatus_dato"
+	colColour        = "farge"
+	colModelYear     = "arsmodell"
+	colEnginePower   = "motorytelse_kw"
+	colDisplacement  = "slagvolum"
+	colCurbWeight    = "egenvekt"
+	colTotalWeight   = "tillatt_totalvekt"
+	colSeats         = "sitteplasser"
+	colDoors         = "dorer"
+	colLastPKK       = "siste_pkk"
+	colLastPKKResult = "siste_pkk_resultat"
+)
+
+// requiredColumns lists the columns that must be present in the CSV header.
+var requiredColumns = []string{colRegNr, colVIN, colGroup, colBrand, colFirstRegDate}
+
+// dateLayouts contains the date formats found in the bulk data.
+var dateLayouts = []string{"2006-01-02", "02.01.2006"}
+
+// columns maps column names to their index in a CSV record.
+type columns map[string]int
+
+// newColumns returns the column indexes from the given CSV header. Column names are matched case insensitively, and a
+// leading byte order mark is ignored.
+func newColumns(header []string) (columns, error) {
+	cols := make(columns, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		cols[name] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("missing column in CSV header: %s", name)
+		}
+	}
+	return cols, nil
+}
+
+// get returns the trimmed value of the named column, or an empty string if the column is not present.
+func (cols columns) get(record []string, name string) string {
+	i, ok := cols[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// vehicle converts a CSV record into a Vehicle from the given source. The hash is not generated.
+func (cols columns) vehicle(record []string, source string) (vehicle.Vehicle, error) {
+	regDate, err := parseDate(cols.get(record, colFirstRegDate))
+	if err != nil {
+		return vehicle.Vehicle{}, fmt.Errorf("unable to parse first registration date: %s", err)
+	}
+	var ident uint64
+	if str := cols.get(record, colIdent); str != "" {
+		if ident, err = strconv.ParseUint(str, 10, 64); err != nil {
+			return vehicle.Vehicle{}, fmt.Errorf("unable to parse vehicle id: %s", str)
+		}
+	}
+	fuel := cols.get(record, colFuel)
+	statusDate, _ := parseDate(cols.get(record, colStatusDate))
+	return vehicle.Vehicle{
+		MetaData:      vehicle.Meta{Source: source, Country: vehicle.NO, Ident: ident, LastUpdated: time.Now(), Disabled: false},
+		Type:          groupToType(cols.get(record, colGroup)),
+		RegNr:         strings.ToUpper(strings.Replace(cols.get(record, colRegNr), " ", "", -1)),
+		VIN:           strings.ToUpper(cols.get(record, colVIN)),
+		Brand:         cols.get(record, colBrand),
+		Model:         cols.get(record, colModel),
+		Variant:       cols.get(record, colVariant),
+		FuelType:      vehicle.PrettyFuelType(fuel),
+		Fuel:          vehicle.FuelTypeFromString(fuel),
+		FirstRegDate:  regDate,
+		RegStatus:     vehicle.RegStatusFromString(cols.get(record, colStatus)),
+		RegStatusDate: statusDate,
+		Tech: vehicle.TechData{
+			EnginePower:  parseNumber(cols.get(record, colEnginePower)),
+			Displacement: parseInt(cols.get(record, colDisplacement)),
+			CurbWeight:   parseInt(cols.get(record, colCurbWeight)),
+			TotalWeight:  parseInt(cols.get(record, colTotalWeight)),
+			Seats:        parseInt(cols.get(record, colSeats)),
+			Doors:        parseInt(cols.get(record, colDoors)),
+			Colour:       vehicle.PrettyColour(cols.get(record, colColour)),
+			ModelYear:    parseInt(cols.get(record, colModelYear)),
+		},
+		LastInspection: cols.inspection(record),
+	}, nil
+}
+
+// inspection returns the latest periodic inspection (PKK, "periodisk kjøretøykontroll") of the vehicle. Vehicles that
+// have never been inspected will have a zero value Inspection.
+func (cols columns) inspection(record []string) vehicle.Inspection {
+	date, err := parseDate(cols.get(record, colLastPKK))
+	if err != nil {
+		return vehicle.Inspection{}
+	}
+	return vehicle.Inspection{Date: date, Type: "PKK", Result: cols.get(record, colLastPKKResult)}
+}
+
+// groupToType maps a technical vehicle group (EU vehicle category, ie. "M1" or "L3e") to a vehicle type.
+func groupToType(group string) vehicle.Type {
+	group = strings.ToUpper(group)
+	switch {
+	case strings.HasPrefix(group, "M1"):
+		return vehicle.Car
+	case strings.HasPrefix(group, "M2"), strings.HasPrefix(group, "M3"):
+		return vehicle.Bus
+	case strings.HasPrefix(group, "N1"):
+		return vehicle.Van
+	case strings.HasPrefix(group, "N2"), strings.HasPrefix(group, "N3"):
+		return vehicle.Truck
+	case strings.HasPrefix(group, "O1"), strings.HasPrefix(group, "O2"):
+		return vehicle.Trailer
+	case strings.HasPrefix(group, "O3"), strings.HasPrefix(group, "O4"):
+		return vehicle.SemiTrailer
+	case strings.HasPrefix(group, "L1"), strings.HasPrefix(group, "L2"):
+		return vehicle.Moped
+	case strings.HasPrefix(group, "L3"), strings.HasPrefix(group, "L4"), strings.HasPrefix(group, "L5"):
+		return vehicle.Motorcycle
+	case strings.HasPrefix(group, "L6"), strings.HasPrefix(group, "L7"):
+		return vehicle.Quadricycle
+	case strings.HasPrefix(group, "T"):
+		return vehicle.Tractor
+	default:
+		return vehicle.Unknown
+	}
+}
+
+// parseDate parses a date in any of the formats used in the bulk data.
+func parseDate(str string) (time.Time, error) {
+	var err error
+	for _, layout := range dateLayouts {
+		var date time.Time
+		if date, err = time.Parse(layout, str); err == nil {
+			return date, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// parseNumber parses a numeric value, which may use a decimal comma. Empty or malformed values result in zero.
+func parseNumber(str string) float64 {
+	num, err := strconv.ParseFloat(strings.Replace(str, ",", ".", 1), 64)
+	if err != nil {
+		return 0
+	}
+	return num
+}
+
+// parseInt parses a numeric value and rounds it to the nearest integer.
+func parseInt(str string) int {
+	return int(math.Round(parseNumber(str)))
+}
@@ -0,0 +1,91 @@
+package vegvesen
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/mkock/autobot/vehicle"
+)
+
+// Service represents Statens vegvesen, the Norwegian Public Roads Administration, which publishes bulk data from the
+// Norwegian vehicle registry as semicolon-separated CSV files.
+type Service struct {
+	source    string
+	types     map[vehicle.Type]bool
+	catalogue *vehicle.Catalogue
+}
+
+// NewService returns a service that can parse vehicle registry data from Statens vegvesen. The vehicles' source is set
+// to the given name, ie. the name of the provider. Only vehicles of the given types will be imported, and their brand,
+// model and variant names are normalised using the given catalogue.
+func NewService(source string, types []vehicle.Type, cat *vehicle.Catalogue) *Service {
+	service := &Service{source: source, types: make(map[vehicle.Type]bool, len(types)), catalogue: cat}
+	for _, t := range types {
+		service.types[t] = true
+	}
+	return service
+}
+
+// processFile reads the CSV file and delivers the parsed vehicles on the "vehicles" channel. Rows that can't be parsed
+// are logged and skipped. It returns an error if the file couldn't be read to the end.
+func (service *Service) processFile(rc io.ReadCloser, vehicles chan<- vehicle.Vehicle) (err error) {
+	defer func() {
+		if closeErr := rc.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	log.Println("Importing...")
+	r := csv.NewReader(rc)
+	r.Comma = ';'
+	r.ReuseRecord = true
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("unable to read CSV header: %s", err)
+	}
+	cols, err := newColumns(header)
+	if err != nil {
+		return err
+	}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); ok {
+				fmt.Printf("Error: %s\n", err)
+				continue
+			}
+			return fmt.Errorf("unable to read CSV file: %s", err)
+		}
+		veh, err := cols.vehicle(record, service.source)
+		if err != nil {
+			line, _ := r.FieldPos(0)
+			fmt.Printf("Error: line %d: %s\n", line, err)
+			continue
+		}
+		if !service.types[veh.Type] {
+			continue
+		}
+		service.catalogue.Normalise(&veh)
+		if err = veh.GenHash(); err != nil {
+			fmt.Println(err.Error())
+			continue
+		}
+		vehicles <- veh
+	}
+}
+
+// LoadNew loads all vehicles from the given CSV file and returns them on a channel, which is closed once all vehicles
+// have been delivered. It then sends nil on channel "done" if the entire file was parsed, or the error that stopped it.
+func (service *Service) LoadNew(rc io.ReadCloser) (vehicles chan vehicle.Vehicle, done chan error) {
+	vehicles, done = make(chan vehicle.Vehicle), make(chan error, 1)
+	go func() {
+		err := service.processFile(rc, vehicles)
+		close(vehicles)
+		done <- err
+	}()
+	return vehicles, done
+}
@@ -0,0 +1,87 @@
+package vegvesen
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mkock/autobot/vehicle"
+)
+
+func loadFixture(t *testing.T, types ...vehicle.Type) []vehicle.Vehicle {
+	file, err := os.Open("testdata/kjoretoy.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vehicles, done := NewService("vegvesen", types, nil).LoadNew(file)
+	var list []vehicle.Vehicle
+	for veh := range vehicles {
+		list = append(list, veh)
+	}
+	if err = <-done; err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+	return list
+}
+
+func TestLoadNewMissingHeader(t *testing.T) {
+	vehicles, done := NewService("vegvesen", []vehicle.Type{vehicle.Car}, nil).LoadNew(ioutil.NopCloser(strings.NewReader("")))
+	for range vehicles {
+	}
+	if err := <-done; err == nil {
+		t.Fatalf("Expected error for empty file but got none")
+	}
+}
+
+func TestLoadNew(t *testing.T) {
+	list := loadFixture(t, vehicle.Car, vehicle.Truck)
+	// The motorcycle is filtered by type, and the last car has a malformed registration date.
+	if len(list) != 3 {
+		t.Fatalf("Expected %v but got %v", 3, len(list))
+	}
+	tesla := list[0]
+	if tesla.MetaData.Country != vehicle.NO {
+		t.Fatalf("Expected %v but got %v", vehicle.NO, tesla.MetaData.Country)
+	}
+	if tesla.MetaData.Source != "vegvesen" {
+		t.Fatalf("Expected %v but got %v", "vegvesen", tesla.MetaData.Source)
+	}
+	if tesla.RegNr != "EL12345" {
+		t.Fatalf("Expected %v but got %v", "EL12345", tesla.RegNr)
+	}
+	if tesla.Brand != "Tesla" {
+		t.Fatalf("Expected %v but got %v", "Tesla", tesla.Brand)
+	}
+	if tesla.Fuel != vehicle.Electric {
+		t.Fatalf("Expected %v but got %v", vehicle.Electric, tesla.Fuel)
+	}
+	if tesla.LastInspection.Type != "PKK" || tesla.LastInspection.Result != "Godkjent" {
+		t.Fatalf("Expected %v but got %v", "PKK Godkjent", tesla.LastInspection)
+	}
+	if tesla.MetaData.Hash == 0 {
+		t.Fatalf("Expected hash but got %v", tesla.MetaData.Hash)
+	}
+	golf := list[1]
+	if golf.RegStatus != vehicle.Deregistered {
+		t.Fatalf("Expected %v but got %v", vehicle.Deregistered, golf.RegStatus)
+	}
+	if golf.FirstRegDate.Format("2006-01-02") != "2008-06-12" {
+		t.Fatalf("Expected %v but got %v", "2008-06-12", golf.FirstRegDate)
+	}
+	if golf.Tech.EnginePower != 90.5 {
+		t.Fatalf("Expected %v but got %v", 90.5, golf.Tech.EnginePower)
+	}
+	if list[2].Type != vehicle.Truck {
+		t.Fatalf("Expected %v but got %v", vehicle.Truck, list[2].Type)
+	}
+}
+
+func TestGroupToType(t *testing.T) {
+	groups := map[string]vehicle.Type{"M1": vehicle.Car, "M1G": vehicle.Car, "M3": vehicle.Bus, "N1": vehicle.Van, "O4": vehicle.SemiTrailer, "L3e": vehicle.Motorcycle, "T1": vehicle.Tractor, "": vehicle.Unknown}
+	for group, expected := range groups {
+		if actual := groupToType(group); actual != expected {
+			t.Fatalf("Expected %v but got %v", expected, actual)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package vehicle
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-redis/redis"
+)
+
+// CatalogueVariant is a canonical variant name with a stable id and the raw names that map to it.
+type CatalogueVariant struct {
+	ID      int
+	Name    string
+	Aliases []string
+}
+
+// CatalogueModel is a canonical model name with a stable id, the raw names that map to it and its variants.
+type CatalogueModel struct {
+	ID      int
+	Name    string
+	Aliases []string
+	Variant []CatalogueVariant
+}
+
+// CatalogueBrand is a canonical brand name with a stable id, the raw names that map to it and its models.
+type CatalogueBrand struct {
+	ID      int
+	Name    string
+	Aliases []string
+	Model   []CatalogueModel
+}
+
+// catalogueFile represents the TOML file that a Catalogue can be loaded from.
+type catalogueFile struct {
+	Brand []CatalogueBrand
+}
+
+type catVariant struct {
+	id      int
+	name    string
+	aliases []string
+}
+
+type catModel struct {
+	id       int
+	name     string
+	aliases  []string
+	variants map[string]*catVariant
+}
+
+type catBrand struct {
+	id      int
+	name    string
+	aliases []string
+	models  map[string]*catModel
+}
+
+// Catalogue maps raw brand, model and variant names, as delivered by the various data sources, to canonical names
+// and stable ids. Lookups are insensitive to case, whitespace and punctuation, so "MERCEDES-BENZ" and "Mercedes Benz"
+// are considered the same name. A Catalogue is safe for concurrent use, and can be extended at runtime and saved
+// back to the file it was loaded from.
+type Catalogue struct {
+	mu     sync.RWMutex
+	saveMu sync.Mutex
+	file   string
+	brands map[string]*catBrand
+}
+
+// NewCatalogue returns a new, empty Catalogue.
+func NewCatalogue() *Catalogue {
+	return &Catalogue{brands: make(map[string]*catBrand)}
+}
+
+// LoadCatalogue returns a Catalogue loaded from the TOML file with the given name. An empty file name results in an
+// empty Catalogue, which can't be saved.
+func LoadCatalogue(fname string) (*Catalogue, error) {
+	cat := NewCatalogue()
+	if fname == "" {
+		return cat, nil
+	}
+	var file catalogueFile
+	if _, err := toml.DecodeFile(fname, &file); err != nil {
+		return nil, err
+	}
+	for _, brand := range file.Brand {
+		cat.AddBrand(brand)
+	}
+	cat.file = fname
+	return cat, nil
+}
+
+// Save writes the Catalogue, including everything added at runtime, back to the file it was loaded from. All
+// entries are written with their ids, in alphabetical order. The file is replaced atomically.
+func (cat *Catalogue) Save() error {
+	if cat.file == "" {
+		return errors.New("catalogue: no catalogue file configured")
+	}
+	cat.saveMu.Lock()
+	defer cat.saveMu.Unlock()
+	tmp, err := ioutil.TempFile(filepath.Dir(cat.file), filepath.Base(cat.file)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once the file has been renamed.
+	if err = toml.NewEncoder(tmp).Encode(cat.export()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cat.file)
+}
+
+// export returns the contents of the Catalogue in the layout of the TOML file.
+func (cat *Catalogue) export() catalogueFile {
+	cat.mu.RLock()
+	defer cat.mu.RUnlock()
+	var file catalogueFile
+	seenBrands := make(map[*catBrand]bool)
+	for _, b := range cat.brands {
+		if seenBrands[b] {
+			continue // Each brand appears once per alias.
+		}
+		seenBrands[b] = true
+		brand := CatalogueBrand{ID: b.id, Name: b.name, Aliases: b.aliases}
+		seenModels := make(map[*catModel]bool)
+		for _, m := range b.models {
+			if seenModels[m] {
+				continue
+			}
+			seenModels[m] = true
+			model := CatalogueModel{ID: m.id, Name: m.name, Aliases: m.aliases}
+			seenVariants := make(map[*catVariant]bool)
+			for _, v := range m.variants {
+				if seenVariants[v] {
+					continue
+				}
+				seenVariants[v] = true
+				model.Variant = append(model.Variant, CatalogueVariant{ID: v.id, Name: v.name, Aliases: v.aliases})
+			}
+			sort.Slice(model.Variant, func(i, j int) bool { return model.Variant[i].Name < model.Variant[j].Name })
+			brand.Model = append(brand.Model, model)
+		}
+		sort.Slice(brand.Model, func(i, j int) bool { return brand.Model[i].Name < brand.Model[j].Name })
+		file.Brand = append(file.Brand, brand)
+	}
+	sort.Slice(file.Brand, func(i, j int) bool { return file.Brand[i].Name < file.Brand[j].Name })
+	return file
+}
+
+// catalogueKey normalises a raw name for lookups by removing everything but letters and digits, and lowercasing it.
+func catalogueKey(name string) string {
+	var key strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			key.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return key.String()
+}
+
+// derivedID returns an id derived from the normalised names of an entry and its parents, ie. brand and model for a
+// variant. It doesn't depend on the order in which entries are added, so it's stable across loads of the catalogue.
+func derivedID(names ...string) int {
+	h := fnv.New32a()
+	for _, name := range names {
+		h.Write([]byte(catalogueKey(name)))
+		h.Write([]byte{0})
+	}
+	return int(h.Sum32() & 0x7fffffff)
+}
+
+// mergeAliases returns the given aliases with the new ones added, leaving out the canonical name and aliases that
+// normalise to the same key as an existing one.
+func mergeAliases(name string, aliases []string, added ...string) []string {
+	seen := map[string]bool{catalogueKey(name): true}
+	for _, alias := range aliases {
+		seen[catalogueKey(alias)] = true
+	}
+	for _, alias := range added {
+		if key := catalogueKey(alias); !seen[key] {
+			seen[key] = true
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
+// AddBrand adds a brand, including its models and variants, to the Catalogue. Entries without an id are assigned one
+// derived from their name, see derivedID. If the brand already exists, its aliases, models and variants are merged
+// into the existing entry.
+func (cat *Catalogue) AddBrand(brand CatalogueBrand) {
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+	b, ok := cat.brands[catalogueKey(brand.Name)]
+	if !ok {
+		id := brand.ID
+		if id == 0 {
+			id = derivedID(brand.Name)
+		}
+		b = &catBrand{id: id, name: brand.Name, models: make(map[string]*catModel)}
+	}
+	b.aliases = mergeAliases(b.name, b.aliases, append(brand.Aliases, brand.Name)...)
+	for _, alias := range append(brand.Aliases, brand.Name) {
+		cat.brands[catalogueKey(alias)] = b
+	}
+	for _, model := range brand.Model {
+		addModel(b, model)
+	}
+}
+
+// AddModel adds a model, including its variants, to an existing brand in the Catalogue.
+func (cat *Catalogue) AddModel(brand string, model CatalogueModel) error {
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+	b, ok := cat.brands[catalogueKey(brand)]
+	if !ok {
+		return fmt.Errorf("catalogue: no such brand: %s", brand)
+	}
+	addModel(b, model)
+	return nil
+}
+
+// AddVariant adds a variant to an existing model in the Catalogue.
+func (cat *Catalogue) AddVariant(brand, model string, variant CatalogueVariant) error {
+	cat.mu.Lock()
+	defer cat.mu.Unlock()
+	b, ok := cat.brands[catalogueKey(brand)]
+	if !ok {
+		return fmt.Errorf("catalogue: no such brand: %s", brand)
+	}
+	m, ok := b.models[catalogueKey(model)]
+	if !ok {
+		return fmt.Errorf("catalogue: no such model: %s %s", brand, model)
+	}
+	addVariant(b, m, variant)
+	return nil
+}
+
+// addModel adds a model to the given brand. The caller must hold the write lock.
+func addModel(b *catBrand, model CatalogueModel) {
+	m, ok := b.models[catalogueKey(model.Name)]
+	if !ok {
+		id := model.ID
+		if id == 0 {
+			id = derivedID(b.name, model.Name)
+		}
+		m = &catModel{id: id, name: model.Name, variants: make(map[string]*catVariant)}
+	}
+	m.aliases = mergeAliases(m.name, m.aliases, append(model.Aliases, model.Name)...)
+	for _, alias := range append(model.Aliases, model.Name) {
+		b.models[catalogueKey(alias)] = m
+	}
+	for _, variant := range model.Variant {
+		addVariant(b, m, variant)
+	}
+}
+
+// addVariant adds a variant to the given model of the given brand. The caller must hold the write lock.
+func addVariant(b *catBrand, m *catModel, variant CatalogueVariant) {
+	v, ok := m.variants[catalogueKey(variant.Name)]
+	if !ok {
+		id := variant.ID
+		if id == 0 {
+			id = derivedID(b.name, m.name, variant.Name)
+		}
+		v = &catVariant{id: id, name: variant.Name}
+	}
+	v.aliases = mergeAliases(v.name, v.aliases, append(variant.Aliases, variant.Name)...)
+	for _, alias := range append(variant.Aliases, variant.Name) {
+		m.variants[catalogueKey(alias)] = v
+	}
+}
+
+// Normalise replaces the brand, model and variant names of the given vehicle with their canonical names, and sets
+// their ids. Brands that are not in the Catalogue are prettified with PrettyBrandName, while unknown models and
+// variants are left untouched. A nil Catalogue only prettifies the brand name.
+func (cat *Catalogue) Normalise(v *Vehicle) {
+	v.BrandID, v.ModelID, v.VariantID = 0, 0, 0
+	if cat == nil {
+		v.Brand = PrettyBrandName(v.Brand)
+		return
+	}
+	cat.mu.RLock()
+	defer cat.mu.RUnlock()
+	b, ok := cat.brands[catalogueKey(v.Brand)]
+	if !ok {
+		v.Brand = PrettyBrandName(v.Brand)
+		return
+	}
+	v.Brand, v.BrandID = b.name, b.id
+	m, ok := b.models[catalogueKey(v.Model)]
+	if !ok {
+		return
+	}
+	v.Model, v.ModelID = m.name, m.id
+	if variant, ok := m.variants[catalogueKey(v.Variant)]; ok {
+		v.Variant, v.VariantID = variant.name, variant.id
+	}
+}
+
+// Renormalise applies the given Catalogue to every vehicle in the store. Vehicles whose brand, model or variant
+// names change get a new hash, so they are replaced in the store and indexes, keeping their metadata and inspection
+// timeline. Their catalog counts move to the new names. It returns the number of vehicles that were updated.
+func (vs *Store) Renormalise(cat *Catalogue) (int, error) {
+	var (
+		updated int
+		keys    []string
+		cur     uint64
+		err     error
+	)
+	for {
+		if keys, cur, err = vs.store.HScan(vs.opts.VehicleMap, cur, "", 100).Result(); err != nil {
+			return updated, err
+		}
+		// HScan returns keys and values interleaved.
+		for i := 1; i < len(keys); i += 2 {
+			var old Vehicle
+			if err = old.Unmarshal(keys[i]); err != nil {
+				return updated, err
+			}
+			veh := old
+			cat.Normalise(&veh)
+			if veh == old {
+				continue
+			}
+			if err = veh.GenHash(); err != nil {
+				return updated, err
+			}
+			if veh.MetaData.Hash == old.MetaData.Hash {
+				err = vs.updateVehicle(veh) // Only the ids changed.
+			} else {
+				err = vs.replaceVehicle(old, veh)
+			}
+			if err != nil {
+				return updated, err
+			}
+			updated++
+		}
+		if cur == 0 {
+			break
+		}
+	}
+	return updated, nil
+}
+
+// replaceVehicle replaces vehicle "old" with "veh", which has a different hash. The old vehicle and its index entries
+// are removed, its catalog count is moved to the new vehicle, and so is its inspection timeline.
+func (vs *Store) replaceVehicle(old, veh Vehicle) error {
+	oldHash := HashAsKey(old.MetaData.Hash)
+	if _, err := vs.store.HDel(vs.opts.VehicleMap, oldHash).Result(); err != nil {
+		return err
+	}
+	if counts(old) {
+		if err := vs.countVehicle(old, -1); err != nil {
+			return err
+		}
+	}
+	if err := vs.remove(fmt.Sprintf("%d:%s:%s", old.MetaData.Country, old.VIN, oldHash), vs.opts.VINSortedSet); err != nil {
+		return err
+	}
+	if err := vs.remove(fmt.Sprintf("%d:%s:%s", old.MetaData.Country, old.RegNr, oldHash), vs.opts.RegNrSortedSet); err != nil {
+		return err
+	}
+	// The timeline is only keyed by hash for vehicles without an Ident.
+	if vs.opts.InspectionSortedSet != "" && vs.inspectionKey(old) != vs.inspectionKey(veh) {
+		if err := vs.moveTimeline(vs.inspectionKey(old), vs.inspectionKey(veh)); err != nil {
+			return err
+		}
+	}
+	// Data-quality rules aren't applied, since the vehicle is already in the store.
+	res, err := vs.syncVehicle(veh)
+	if err == nil && res == added && counts(veh) {
+		err = vs.countVehicle(veh, 1)
+	}
+	return err
+}
+
+// moveTimeline moves the inspection timeline at key "from" to key "to". If there's already a timeline at "to", the
+// two are merged, so inspections aren't lost.
+func (vs *Store) moveTimeline(from, to string) error {
+	renamed, err := vs.store.RenameNX(from, to).Result()
+	if err != nil {
+		if isNoSuchKey(err) {
+			return nil
+		}
+		return err
+	}
+	if renamed {
+		return nil
+	}
+	if _, err = vs.store.ZUnionStore(to, redis.ZStore{Aggregate: "MAX"}, to, from).Result(); err != nil {
+		return err
+	}
+	_, err = vs.store.Del(from).Result()
+	return err
+}
+
+// isNoSuchKey reports whether the given error is Redis' response to an operation on a missing key.
+func isNoSuchKey(err error) bool {
+	return err != redis.Nil && strings.Contains(err.Error(), "no such key")
+}
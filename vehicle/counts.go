@@ -0,0 +1,254 @@
+package vehicle
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/go-redis/redis"
+)
+
+// CatalogEntry contains the number of vehicles in the store with a given brand, model or variant, and the range of
+// first registration years of those vehicles.
+type CatalogEntry struct {
+	Name      string
+	Count     int
+	FirstYear int
+	LastYear  int
+}
+
+// merge adds the count and year range of "other" to the entry.
+func (entry *CatalogEntry) merge(other CatalogEntry) {
+	if entry.Name == "" {
+		entry.Name = other.Name
+	}
+	if entry.FirstYear == 0 || (other.FirstYear != 0 && other.FirstYear < entry.FirstYear) {
+		entry.FirstYear = other.FirstYear
+	}
+	if other.LastYear > entry.LastYear {
+		entry.LastYear = other.LastYear
+	}
+	entry.Count += other.Count
+}
+
+// catalogTally collects catalog changes in memory during a sync, so the store only needs to be updated once per
+// brand, model and variant. Entries are grouped by catalog key (without prefix) and then by normalised name.
+type catalogTally map[string]map[string]CatalogEntry
+
+// brandsKey returns the catalog key for brands, relative to the configured CatalogHash prefix.
+func brandsKey() string {
+	return "brands"
+}
+
+// modelsKey returns the catalog key for the models of the given brand.
+func modelsKey(brand string) string {
+	return "models:" + catalogueKey(brand)
+}
+
+// variantsKey returns the catalog key for the variants of the given brand and model.
+func variantsKey(brand, model string) string {
+	return "variants:" + catalogueKey(brand) + ":" + catalogueKey(model)
+}
+
+// counts reports whether the given vehicle is counted in the catalog. Disabled and deregistered vehicles are not.
+func counts(v Vehicle) bool {
+	return !v.MetaData.Disabled && !v.MetaData.Deregistered
+}
+
+// add tallies the brand, model and variant of the given vehicle.
+func (ct catalogTally) add(v Vehicle) {
+	year := v.FirstRegDate.Year()
+	ct.addVehicle(v, CatalogEntry{Count: 1, FirstYear: year, LastYear: year})
+}
+
+// remove subtracts the brand, model and variant of the given vehicle from the tally. The year ranges are left as-is,
+// since they can only be narrowed by looking at all vehicles, see RebuildCatalog.
+func (ct catalogTally) remove(v Vehicle) {
+	ct.addVehicle(v, CatalogEntry{Count: -1})
+}
+
+// addVehicle merges the given entry into the entries for the brand, model and variant of the given vehicle.
+func (ct catalogTally) addVehicle(v Vehicle, entry CatalogEntry) {
+	ct.addEntry(brandsKey(), v.Brand, entry)
+	if v.Model == "" {
+		return
+	}
+	ct.addEntry(modelsKey(v.Brand), v.Model, entry)
+	if v.Variant == "" {
+		return
+	}
+	ct.addEntry(variantsKey(v.Brand, v.Model), v.Variant, entry)
+}
+
+// addEntry merges the given entry into the entry with the given name for the given key.
+func (ct catalogTally) addEntry(key, name string, other CatalogEntry) {
+	if name == "" {
+		return
+	}
+	if ct[key] == nil {
+		ct[key] = make(map[string]CatalogEntry)
+	}
+	other.Name = name
+	entry := ct[key][catalogueKey(name)]
+	entry.merge(other)
+	ct[key][catalogueKey(name)] = entry
+}
+
+// catalogKey returns the full key of the hash containing catalog entries for the given relative key.
+func (vs *Store) catalogKey(key string) string {
+	return vs.opts.CatalogHash + ":" + key
+}
+
+// updateCatalog merges the tallied catalog entries into the catalog in the store. Entries whose count drops to zero
+// are removed.
+func (vs *Store) updateCatalog(tally catalogTally) error {
+	if vs.opts.CatalogHash == "" {
+		return nil
+	}
+	for key, entries := range tally {
+		fullKey := vs.catalogKey(key)
+		for field, entry := range entries {
+			str, err := vs.store.HGet(fullKey, field).Result()
+			if err == nil {
+				var existing CatalogEntry
+				if err = json.Unmarshal([]byte(str), &existing); err != nil {
+					return err
+				}
+				existing.merge(entry)
+				entry = existing
+			} else if err != redis.Nil {
+				return err
+			}
+			if entry.Count <= 0 {
+				if _, err = vs.store.HDel(fullKey, field).Result(); err != nil {
+					return err
+				}
+				continue
+			}
+			b, err := json.Marshal(entry)
+			if err != nil {
+				return err
+			}
+			if _, err = vs.store.HSet(fullKey, field, string(b)).Result(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// countVehicle adds the given vehicle to the catalog, or subtracts it if "delta" is negative.
+func (vs *Store) countVehicle(veh Vehicle, delta int) error {
+	tally := make(catalogTally)
+	if delta < 0 {
+		tally.remove(veh)
+	} else {
+		tally.add(veh)
+	}
+	return vs.updateCatalog(tally)
+}
+
+// catalogEntries returns all catalog entries for the given relative key, sorted by name.
+func (vs *Store) catalogEntries(key string) ([]CatalogEntry, error) {
+	if vs.opts.CatalogHash == "" {
+		return nil, nil
+	}
+	vals, err := vs.store.HVals(vs.catalogKey(key)).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]CatalogEntry, len(vals))
+	for i, val := range vals {
+		if err = json.Unmarshal([]byte(val), &entries[i]); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+// CatalogBrands returns all brands in the store, sorted by name.
+func (vs *Store) CatalogBrands() ([]CatalogEntry, error) {
+	return vs.catalogEntries(brandsKey())
+}
+
+// CatalogModels returns all models of the given brand, sorted by name. The brand name is matched regardless of case,
+// whitespace and punctuation.
+func (vs *Store) CatalogModels(brand string) ([]CatalogEntry, error) {
+	return vs.catalogEntries(modelsKey(brand))
+}
+
+// CatalogVariants returns all variants of the given brand and model, sorted by name.
+func (vs *Store) CatalogVariants(brand, model string) ([]CatalogEntry, error) {
+	return vs.catalogEntries(variantsKey(brand, model))
+}
+
+// clearCatalog deletes the entire catalog.
+func (vs *Store) clearCatalog() error {
+	if vs.opts.CatalogHash == "" {
+		return nil
+	}
+	return vs.deleteMatching(vs.catalogKey("*"))
+}
+
+// RebuildCatalog rebuilds the catalog from scratch, based on all vehicles in the store that are neither disabled nor
+// deregistered. The old catalog is replaced in a single transaction, so readers never see a partial catalog. Counts
+// are otherwise kept up to date as vehicles change, so this is only needed to narrow the year ranges, which are never
+// narrowed when vehicles are removed.
+func (vs *Store) RebuildCatalog() error {
+	var (
+		keys []string
+		cur  uint64
+		err  error
+		veh  Vehicle
+	)
+	if vs.opts.CatalogHash == "" {
+		return nil
+	}
+	tally := make(catalogTally)
+	for {
+		if keys, cur, err = vs.store.HScan(vs.opts.VehicleMap, cur, "", 100).Result(); err != nil {
+			return err
+		}
+		// HScan returns keys and values interleaved.
+		for i := 1; i < len(keys); i += 2 {
+			veh = Vehicle{}
+			if err = veh.Unmarshal(keys[i]); err != nil {
+				return err
+			}
+			if counts(veh) {
+				tally.add(veh)
+			}
+		}
+		if cur == 0 {
+			break
+		}
+	}
+	var old []string
+	for cur = 0; ; {
+		if keys, cur, err = vs.store.Scan(cur, vs.catalogKey("*"), 100).Result(); err != nil {
+			return err
+		}
+		old = append(old, keys...)
+		if cur == 0 {
+			break
+		}
+	}
+	_, err = vs.store.TxPipelined(func(pipe redis.Pipeliner) error {
+		if len(old) > 0 {
+			pipe.Del(old...)
+		}
+		for key, entries := range tally {
+			fields := make(map[string]interface{}, len(entries))
+			for field, entry := range entries {
+				b, err := json.Marshal(entry)
+				if err != nil {
+					return err
+				}
+				fields[field] = string(b)
+			}
+			pipe.HMSet(vs.catalogKey(key), fields)
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,149 @@
+package vehicle
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Exported errors.
+var (
+	ErrNoSeenSet = errors.New("detection of deregistered vehicles requires a SeenSet in the sync configuration")
+)
+
+// clearSeen empties the set of vehicles that were seen during the previous synchronisation.
+func (vs *Store) clearSeen() error {
+	if vs.opts.SeenSet == "" {
+		return ErrNoSeenSet
+	}
+	if _, err := vs.store.Del(vs.opts.SeenSet).Result(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// seenBatchSize is the number of seen vehicles that are added to the SeenSet in a single round-trip.
+const seenBatchSize = 1000
+
+// markSeen remembers that the vehicles with the given hashes were present in the data source during the current
+// synchronisation.
+func (vs *Store) markSeen(hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+	members := make([]interface{}, len(hashes))
+	for i, hash := range hashes {
+		members[i] = hash
+	}
+	if _, err := vs.store.SAdd(vs.opts.SeenSet, members...).Result(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// exceedsThreshold reports whether the number of vehicles that would be marked as deregistered is larger than the
+// allowed fraction of the total number of vehicles.
+func exceedsThreshold(candidates, total int, threshold float64) bool {
+	if total == 0 {
+		return candidates > 0
+	}
+	return float64(candidates)/float64(total) > threshold
+}
+
+// markDeregistered scans the vehicle store for vehicles that were not seen during the synchronisation with the given
+// sync operation, and marks them as deregistered. Only vehicles whose source is the provider of the sync operation are
+// considered, so vehicles from other providers, imports and lookups are never marked. Vehicles that were previously marked as deregistered, but have reappeared, are reactivated.
+// If the number of vehicles to mark exceeds the configured threshold, the marking is skipped entirely.
+func (vs *Store) markDeregistered(op *syncOp) error {
+	var (
+		total      int
+		keys       []string
+		cur        uint64
+		err        error
+		seen       []bool
+		veh        Vehicle
+		batch      []Vehicle
+		missing    []Vehicle
+		reappeared []Vehicle
+	)
+	for {
+		if keys, cur, err = vs.store.HScan(vs.opts.VehicleMap, cur, "", 100).Result(); err != nil {
+			return err
+		}
+		// HScan returns keys and values interleaved, so we only need every other entry.
+		batch = batch[:0]
+		for i := 1; i < len(keys); i += 2 {
+			veh = Vehicle{}
+			if err = veh.Unmarshal(keys[i]); err != nil {
+				return err
+			}
+			if veh.MetaData.Source != op.source {
+				continue
+			}
+			total++
+			batch = append(batch, veh)
+		}
+		if seen, err = vs.seenBatch(batch); err != nil {
+			return err
+		}
+		for i, veh := range batch {
+			if seen[i] && veh.MetaData.Deregistered {
+				reappeared = append(reappeared, veh)
+			} else if !seen[i] && !veh.MetaData.Deregistered {
+				missing = append(missing, veh)
+			}
+		}
+		if cur == 0 {
+			break
+		}
+	}
+	for _, veh = range reappeared {
+		veh.MetaData.Deregistered = false
+		veh.MetaData.DeregisteredAt = time.Time{}
+		if err = vs.updateVehicle(veh); err != nil {
+			return err
+		}
+		if counts(veh) {
+			op.tally.add(veh)
+		}
+	}
+	if exceedsThreshold(len(missing), total, vs.opts.DeregisterThreshold) {
+		fmt.Fprintf(vs.logger, "Notice: %d of %d vehicles are missing from the data source, which exceeds the threshold. Skipping deregistration.\n", len(missing), total)
+		return nil
+	}
+	now := time.Now()
+	for _, veh = range missing {
+		veh.MetaData.Deregistered = true
+		veh.MetaData.DeregisteredAt = now
+		if err = vs.updateVehicle(veh); err != nil {
+			return err
+		}
+		if !veh.MetaData.Disabled {
+			op.tally.remove(veh)
+		}
+		op.deregistered++
+	}
+	return vs.clearSeen()
+}
+
+// seenBatch checks which of the given vehicles were seen during the current synchronisation, in a single round-trip.
+func (vs *Store) seenBatch(vehicles []Vehicle) ([]bool, error) {
+	if len(vehicles) == 0 {
+		return nil, nil
+	}
+	pipe := vs.store.Pipeline()
+	cmds := make([]*redis.BoolCmd, len(vehicles))
+	for i, veh := range vehicles {
+		cmds[i] = pipe.SIsMember(vs.opts.SeenSet, HashAsKey(veh.MetaData.Hash))
+	}
+	if _, err := pipe.Exec(); err != nil {
+		return nil, err
+	}
+	seen := make([]bool, len(cmds))
+	for i, cmd := range cmds {
+		seen[i] = cmd.Val()
+	}
+	return seen, nil
+}
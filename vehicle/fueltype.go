@@ -0,0 +1,79 @@
+package vehicle
+
+import "strings"
+
+// FuelType represents the normalised fuel type of a vehicle. The raw fuel type from the data source is kept in
+// Vehicle.FuelType, while the normalised fuel type is kept in Vehicle.Fuel.
+type FuelType int
+
+// List of supported fuel types.
+const (
+	UnknownFuel FuelType = iota
+	Petrol
+	Diesel
+	Electric
+	Hybrid
+	PluginHybrid
+	Hydrogen
+	Gas
+	OtherFuel
+)
+
+// String returns the string representation of the fuel type.
+func (ft FuelType) String() string {
+	switch ft {
+	case Petrol:
+		return "Petrol"
+	case Diesel:
+		return "Diesel"
+	case Electric:
+		return "Electric"
+	case Hybrid:
+		return "Hybrid"
+	case PluginHybrid:
+		return "PluginHybrid"
+	case Hydrogen:
+		return "Hydrogen"
+	case Gas:
+		return "Gas"
+	case OtherFuel:
+		return "Other"
+	default:
+		return "Unknown"
+	}
+}
+
+// FuelTypeFromString returns the FuelType that matches the given string (case insensitive match). It recognises the
+// enum names as well as the English, Danish and Norwegian fuel type names used by DMR, nrpla.de and Statens vegvesen.
+// Unrecognised, non-empty strings result in OtherFuel, while an empty string results in UnknownFuel.
+func FuelTypeFromString(str string) FuelType {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "":
+		return UnknownFuel
+	case "unknown":
+		return UnknownFuel
+	case "petrol", "benzin", "bensin", "gasoline":
+		return Petrol
+	case "diesel":
+		return Diesel
+	case "electric", "electricity", "el", "elektrisk", "elektrisitet":
+		return Electric
+	case "hybrid", "benzin/el", "diesel/el", "el/benzin", "el/diesel", "bensin/el", "el/bensin":
+		return Hybrid
+	case "pluginhybrid", "plug-in hybrid", "plugin hybrid", "plug-in-hybrid":
+		return PluginHybrid
+	case "hydrogen", "brint":
+		return Hydrogen
+	case "gas", "gass", "lpg", "cng", "lpg/cng", "f-gas", "n-gas", "autogas", "naturgas":
+		return Gas
+	default:
+		return OtherFuel
+	}
+}
+
+// knownFuelType reports whether the given string maps to one of the specific fuel types, ie. not UnknownFuel
+// or OtherFuel.
+func knownFuelType(str string) bool {
+	ft := FuelTypeFromString(str)
+	return ft != UnknownFuel && ft != OtherFuel
+}
@@ -0,0 +1,101 @@
+package vehicle
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Inspection represents a single periodic inspection of a vehicle, including the odometer reading (in km) at the
+// time of inspection.
+type Inspection struct {
+	Date     time.Time
+	Type     string
+	Result   string
+	Odometer int
+}
+
+// IsZero reports whether the inspection is empty, ie. the vehicle has never been inspected.
+func (insp Inspection) IsZero() bool {
+	return insp.Date.IsZero()
+}
+
+// String returns a human readable representation of the inspection.
+func (insp Inspection) String() string {
+	return insp.TranslatedString(enumNames{})
+}
+
+// TranslatedString works like String, but uses the given Translator for the inspection type and result.
+func (insp Inspection) TranslatedString(tr Translator) string {
+	return fmt.Sprintf("%s %s: %s, %d km", insp.Date.Format("2006-01-02"), tr.InspectionTerm(insp.Type), tr.InspectionTerm(insp.Result), insp.Odometer)
+}
+
+// InspectionTimeline contains all known inspections of a vehicle, oldest first.
+type InspectionTimeline []Inspection
+
+// LatestOdometer returns the most recent odometer reading and the date it was recorded.
+// It returns zero values if no inspection contains an odometer reading.
+func (tl InspectionTimeline) LatestOdometer() (int, time.Time) {
+	for i := len(tl) - 1; i >= 0; i-- {
+		if tl[i].Odometer > 0 {
+			return tl[i].Odometer, tl[i].Date
+		}
+	}
+	return 0, time.Time{}
+}
+
+// inspectionKey returns the key of the sorted set containing the inspection timeline for the given vehicle. The key is
+// made up of the vehicle's country and Ident, since those stay the same when the vehicle gets a new hash, ie. because
+// of a new registration number or a renormalised brand name. Vehicles without an Ident, such as vehicles from direct
+// lookups, fall back to their hash.
+func (vs *Store) inspectionKey(veh Vehicle) string {
+	if veh.MetaData.Ident == 0 {
+		return vs.opts.InspectionSortedSet + ":" + HashAsKey(veh.MetaData.Hash)
+	}
+	return fmt.Sprintf("%s:%s:%d", vs.opts.InspectionSortedSet, veh.MetaData.Country, veh.MetaData.Ident)
+}
+
+// addInspection adds the latest inspection of the given vehicle to its timeline. Inspections are scored by date, and
+// identical inspections are only stored once. Nothing is stored if inspections are not configured.
+func (vs *Store) addInspection(veh Vehicle) error {
+	insp := veh.LastInspection
+	if insp.IsZero() || vs.opts.InspectionSortedSet == "" {
+		return nil
+	}
+	b, err := json.Marshal(insp)
+	if err != nil {
+		return err
+	}
+	zInsp := redis.Z{Score: float64(insp.Date.Unix()), Member: string(b)}
+	if _, err = vs.store.ZAdd(vs.inspectionKey(veh), zInsp).Result(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Inspections returns the inspection timeline for the given vehicle, oldest first.
+func (vs *Store) Inspections(veh Vehicle) (InspectionTimeline, error) {
+	members, err := vs.store.ZRange(vs.inspectionKey(veh), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	timeline := make(InspectionTimeline, 0, len(members))
+	for _, member := range members {
+		var insp Inspection
+		if err = json.Unmarshal([]byte(member), &insp); err != nil {
+			return nil, err
+		}
+		timeline = append(timeline, insp)
+	}
+	return timeline, nil
+}
+
+// clearInspections deletes the inspection timelines of all vehicles.
+func (vs *Store) clearInspections() error {
+	if vs.opts.InspectionSortedSet == "" {
+		return nil
+	}
+	return vs.deleteMatching(vs.opts.InspectionSortedSet + ":*")
+}
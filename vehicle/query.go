@@ -2,13 +2,16 @@ package vehicle
 
 import "strings"
 
-// Query contains the search- and filter options for performing a query against the store.
+// Query contains the search- and filter options for performing a query against the store. If Translator is set, the
+// fuel and registration status of the results are translated, otherwise their enum names are used.
 type Query struct {
-	Limit    int64
-	Type     string
-	Brand    string
-	Model    string
-	FuelType string
+	Limit      int64
+	Type       string
+	Brand      string
+	Model      string
+	FuelType   string
+	RegStatus  string
+	Translator Translator
 }
 
 type preparedQuery struct {
@@ -18,6 +21,10 @@ type preparedQuery struct {
 	brand       string
 	model       string
 	fuelType    string
+	fuel        FuelType
+	byFuel      bool
+	regStatus   RegStatus
+	byRegStatus bool
 }
 
 func (pq preparedQuery) validates(v Vehicle) bool {
@@ -40,15 +47,30 @@ func (pq preparedQuery) validates(v Vehicle) bool {
 			passed++
 		}
 	}
-	if pq.fuelType != "" {
+	if pq.byFuel {
+		checks++
+		if v.NormalisedFuel() == pq.fuel {
+			passed++
+		}
+	} else if pq.fuelType != "" {
 		checks++
 		if strings.EqualFold(v.FuelType, pq.fuelType) {
 			passed++
 		}
 	}
+	if pq.byRegStatus {
+		checks++
+		if v.RegStatus == pq.regStatus {
+			passed++
+		}
+	}
 	return passed == checks
 }
 
+// prepareQuery converts the given Query into a preparedQuery. Fuel types that map to a specific FuelType, such as
+// "Diesel" or "Benzin", are matched against the normalised fuel type, while others are matched against the raw one.
 func prepareQuery(q Query) preparedQuery {
-	return preparedQuery{limit: q.Limit, vehicleType: TypeFromString(q.Type), byType: q.Type != "", brand: q.Brand, model: q.Model, fuelType: q.FuelType}
+	return preparedQuery{limit: q.Limit, vehicleType: TypeFromString(q.Type), byType: q.Type != "", brand: q.Brand, model: q.Model,
+		fuelType: q.FuelType, fuel: FuelTypeFromString(q.FuelType), byFuel: knownFuelType(q.FuelType),
+		regStatus: RegStatusFromString(q.RegStatus), byRegStatus: q.RegStatus != ""}
 }
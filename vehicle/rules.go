@@ -0,0 +1,121 @@
+package vehicle
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mkock/autobot/config"
+)
+
+// Names of the data-quality rules. They are used as keys for the rejection counters on the sync operation.
+const (
+	RuleMissingVIN    = "missing-vin"
+	RuleMalformedVIN  = "malformed-vin"
+	RuleVINCheckDigit = "vin-check-digit"
+	RuleFutureRegDate = "future-reg-date"
+	RuleBlockedBrand  = "blocked-brand"
+)
+
+// rule is a single data-quality rule. Reject returns true if the vehicle violates the rule.
+type rule struct {
+	name   string
+	reject func(Vehicle) bool
+}
+
+// RuleSet is a list of data-quality rules that vehicles must satisfy before they are added to the store.
+type RuleSet struct {
+	rules []rule
+}
+
+// NewRuleSet returns a RuleSet containing the rules that are enabled in the given configuration.
+func NewRuleSet(cnf config.RulesConfig) *RuleSet {
+	rs := &RuleSet{}
+	if cnf.RejectMissingVIN {
+		rs.rules = append(rs.rules, rule{RuleMissingVIN, func(v Vehicle) bool {
+			return strings.TrimSpace(v.VIN) == ""
+		}})
+	}
+	if cnf.RejectMalformedVIN {
+		rs.rules = append(rs.rules, rule{RuleMalformedVIN, func(v Vehicle) bool {
+			return v.VIN != "" && !WellFormedVIN(v.VIN)
+		}})
+	}
+	if cnf.RejectVINCheckDigit {
+		rs.rules = append(rs.rules, rule{RuleVINCheckDigit, func(v Vehicle) bool {
+			return WellFormedVIN(v.VIN) && !ValidVINCheckDigit(v.VIN)
+		}})
+	}
+	if cnf.RejectFutureRegDate {
+		rs.rules = append(rs.rules, rule{RuleFutureRegDate, func(v Vehicle) bool {
+			return v.FirstRegDate.After(time.Now())
+		}})
+	}
+	if len(cnf.BrandBlocklist) > 0 {
+		// Brands are compared by their catalogue key, so "Mercedes-Benz" also blocks "MERCEDES BENZ".
+		blocked := make(map[string]bool, len(cnf.BrandBlocklist))
+		for _, brand := range cnf.BrandBlocklist {
+			blocked[catalogueKey(brand)] = true
+		}
+		rs.rules = append(rs.rules, rule{RuleBlockedBrand, func(v Vehicle) bool {
+			return blocked[catalogueKey(v.Brand)]
+		}})
+	}
+	return rs
+}
+
+// Reject returns the name of the first rule that the given vehicle violates, or an empty string if the vehicle
+// satisfies all rules.
+func (rs *RuleSet) Reject(v Vehicle) string {
+	if rs == nil {
+		return ""
+	}
+	for _, r := range rs.rules {
+		if r.reject(v) {
+			return r.name
+		}
+	}
+	return ""
+}
+
+// vinValues contains the transliteration values of VIN characters, used for calculating the check digit.
+var vinValues = map[rune]int{
+	'A': 1, 'B': 2, 'C': 3, 'D': 4, 'E': 5, 'F': 6, 'G': 7, 'H': 8,
+	'J': 1, 'K': 2, 'L': 3, 'M': 4, 'N': 5, 'P': 7, 'R': 9,
+	'S': 2, 'T': 3, 'U': 4, 'V': 5, 'W': 6, 'X': 7, 'Y': 8, 'Z': 9,
+	'0': 0, '1': 1, '2': 2, '3': 3, '4': 4, '5': 5, '6': 6, '7': 7, '8': 8, '9': 9,
+}
+
+// vinWeights contains the weight of each VIN position, used for calculating the check digit.
+var vinWeights = [17]int{8, 7, 6, 5, 4, 3, 2, 10, 0, 9, 8, 7, 6, 5, 4, 3, 2}
+
+// WellFormedVIN reports whether the given VIN is 17 characters long and only contains valid characters,
+// ie. digits and letters except I, O and Q.
+func WellFormedVIN(vin string) bool {
+	if len(vin) != 17 {
+		return false
+	}
+	for _, c := range strings.ToUpper(vin) {
+		if _, ok := vinValues[c]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidVINCheckDigit reports whether the check digit (9th character) of the given VIN is correct according to
+// ISO 3779. Note that the check digit is only mandatory in North America, so many European VINs will fail this check.
+func ValidVINCheckDigit(vin string) bool {
+	if !WellFormedVIN(vin) {
+		return false
+	}
+	vin = strings.ToUpper(vin)
+	sum := 0
+	for i, c := range vin {
+		sum += vinValues[c] * vinWeights[i]
+	}
+	check := byte('0' + sum%11)
+	if sum%11 == 10 {
+		check = 'X'
+	}
+	return vin[8] == check
+}
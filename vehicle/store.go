@@ -23,13 +23,24 @@ type Store struct {
 	cnf    config.MemStoreConfig
 	opts   config.SyncConfig
 	store  *redis.Client
+	rules  *RuleSet
 	ops    []syncOp
 	logger io.Writer
 }
 
 // NewStore returns a new Store, which you can then interact with in order to start sync operations etc.
 func NewStore(storeCnf config.MemStoreConfig, syncCnf config.SyncConfig, logger io.Writer) *Store {
-	return &Store{cnf: storeCnf, opts: syncCnf, logger: logger}
+	return &Store{cnf: storeCnf, opts: syncCnf, rules: NewRuleSet(syncCnf.Rules), logger: logger}
+}
+
+// RejectedError is returned by SyncVehicle for vehicles that violate a data-quality rule.
+type RejectedError struct {
+	Rule string
+}
+
+// Error returns the error message, including the name of the violated rule.
+func (e RejectedError) Error() string {
+	return fmt.Sprintf("rejected by rule %q", e.Rule)
 }
 
 // Open connects to the vehicle store.
@@ -50,16 +61,21 @@ func (vs *Store) Close() error {
 	return vs.store.Close()
 }
 
-// NewSyncOp starts a new synchronization operation and returns its id.
+// NewSyncOp starts a new synchronization operation for the provider with the given name and returns its id.
 // Use this id for any further interactions with the operation. Note that his function is not thread-safe.
-func (vs *Store) NewSyncOp(source string) SyncOpID {
+// If detectDeregistered is true, vehicles from the provider that are missing from the data source are marked as
+// deregistered once the operation completes, so it should only be set for data sources that deliver full dumps.
+func (vs *Store) NewSyncOp(source string, detectDeregistered bool) SyncOpID {
 	id := SyncOpID(len(vs.ops))
 	op := syncOp{
-		id:        id,
-		started:   time.Now(),
-		source:    source,
-		processed: 0,
-		synced:    0,
+		id:                 id,
+		started:            time.Now(),
+		source:             source,
+		processed:          0,
+		synced:             0,
+		detectDeregistered: detectDeregistered,
+		rejected:           make(map[string]int),
+		tally:              make(catalogTally),
 	}
 	vs.ops = append(vs.ops, op)
 	return id
@@ -106,36 +122,82 @@ func (vs *Store) writeToFile(vehicles List, outFile string) {
 	fmt.Fprintln(vs.logger, "Wrote processed vehicle list to out.csv")
 }
 
-// Sync reads from channel "vehicles" and synchronizes each one with the store. It stops when receiving a bool on
-// channel "done". Along the way, it keeps track of the number of vehicles that were processed and synchronized.
-// This data is stored on the syncOp.
-func (vs *Store) Sync(id SyncOpID, vehicles <-chan Vehicle, done <-chan bool) error {
+// Sync reads from channel "vehicles" and synchronizes each one with the store, until the parser closes the channel.
+// The parser then sends the outcome of parsing on channel "done": nil if the entire data file was parsed, or the
+// error that stopped it. Each vehicle is stored with the name of the provider as its source. Along the way, Sync keeps track of the number of vehicles that were processed and
+// synchronized. This data is stored on the syncOp. Vehicles that violate a data-quality rule are counted per rule and
+// skipped. If the sync operation detects deregistered vehicles, every processed vehicle is remembered, and once the
+// entire data file has been parsed, vehicles that were not seen are marked as deregistered. Incomplete data files
+// never lead to vehicles being marked, and Sync returns the parser's error for them.
+func (vs *Store) Sync(id SyncOpID, vehicles <-chan Vehicle, done <-chan error) error {
 	var (
-		ok      bool
-		err     error
-		vehicle Vehicle
+		res  syncResult
+		err  error
+		seen []string // Hashes of seen vehicles that haven't been added to the SeenSet yet.
 	)
 	op := vs.getOp(id)
-	for {
-		select {
-		case vehicle = <-vehicles:
-			op.processed++
-			// Only synchronise vehicles that satisfy the limit on reg.date.
-			if vehicle.FirstRegDate.After(vs.opts.EarliestRegDate.Time) {
-				ok, err = vs.SyncVehicle(vehicle)
-				if err != nil {
+	if op.detectDeregistered {
+		if err = vs.clearSeen(); err != nil {
+			go drain(vehicles, done)
+			return err
+		}
+	}
+	for vehicle := range vehicles {
+		op.processed++
+		vehicle.MetaData.Source = op.source
+		if op.detectDeregistered {
+			if seen = append(seen, HashAsKey(vehicle.MetaData.Hash)); len(seen) == seenBatchSize {
+				if err = vs.markSeen(seen); err != nil {
+					go drain(vehicles, done)
 					return err
 				}
-				if ok {
-					op.synced++
-				}
+				seen = seen[:0]
 			}
-		case <-done:
-			vs.Log(op.String())
-			vs.finalize(id)
-			return nil
 		}
+		if rule := vs.rules.Reject(vehicle); rule != "" {
+			op.rejected[rule]++
+			continue
+		}
+		// Only synchronise vehicles that satisfy the limit on reg.date.
+		if vehicle.FirstRegDate.After(vs.opts.EarliestRegDate.Time) {
+			res, err = vs.syncVehicle(vehicle)
+			if err != nil {
+				go drain(vehicles, done)
+				return err
+			}
+			if res != notSynced {
+				op.synced++
+			}
+			if res == added && counts(vehicle) {
+				op.tally.add(vehicle)
+			}
+		}
+	}
+	parseErr := <-done
+	if op.detectDeregistered && parseErr == nil {
+		if err = vs.markSeen(seen); err != nil {
+			return err
+		}
+		if err = vs.markDeregistered(op); err != nil {
+			return err
+		}
+	}
+	if err = vs.updateCatalog(op.tally); err != nil {
+		return err
+	}
+	vs.Log(op.String())
+	vs.finalize(id)
+	if parseErr != nil {
+		return fmt.Errorf("incomplete data file, deregistration skipped: %s", parseErr)
+	}
+	return nil
+}
+
+// drain discards the remaining vehicles from a parser, so it isn't blocked forever when a sync is aborted.
+func drain(vehicles <-chan Vehicle, done <-chan error) {
+	for range vehicles {
 	}
+	<-done
 }
 
 // updateVehicle stores any changes made to the vehicle.
@@ -154,28 +216,100 @@ func (vs *Store) updateVehicle(veh Vehicle) error {
 	return nil
 }
 
+// syncResult describes the outcome of synchronising a single vehicle.
+type syncResult int
+
+// List of possible outcomes when synchronising a vehicle.
+const (
+	notSynced syncResult = iota
+	added
+	refreshed
+)
+
 // SyncVehicle synchronizes a single Vehicle with the memory store.
-// It returns a bool indicating whether the vehicle was added/updated or not.
+// It returns a bool indicating whether the vehicle was added/updated or not. Vehicles that already exist are only
+// updated if their registration status, technical data or latest inspection has changed. New inspections are added
+// to the vehicle's inspection timeline. Vehicles that violate a data-quality rule are not synchronised, and a
+// RejectedError is returned.
 func (vs *Store) SyncVehicle(veh Vehicle) (bool, error) {
-	mapName := vs.opts.VehicleMap
+	if rule := vs.rules.Reject(veh); rule != "" {
+		return false, RejectedError{rule}
+	}
+	res, err := vs.syncVehicle(veh)
+	if err == nil && res == added && counts(veh) {
+		err = vs.countVehicle(veh, 1)
+	}
+	return res != notSynced, err
+}
+
+// syncVehicle does the actual work for SyncVehicle, but reports whether the vehicle was added or refreshed.
+func (vs *Store) syncVehicle(veh Vehicle) (syncResult, error) {
 	vinIndex := vs.opts.VINSortedSet
 	regIndex := vs.opts.RegNrSortedSet
 	hash := HashAsKey(veh.MetaData.Hash)
-	exists, err := vs.store.HExists(mapName, hash).Result()
-	if err != nil || exists {
-		return false, err
+	existing, err := vs.lookupVehicleSimple(hash)
+	if err != nil {
+		return notSynced, err
+	}
+	if err = vs.addInspection(veh); err != nil {
+		return notSynced, err
+	}
+	if existing != (Vehicle{}) {
+		ok, err := vs.refresh(existing, veh)
+		if err != nil || !ok {
+			return notSynced, err
+		}
+		return refreshed, nil
 	}
 	if err := vs.updateVehicle(veh); err != nil {
-		return false, err
+		return notSynced, err
 	}
 	// Update the VIN index.
 	zVIN := redis.Z{Score: 0, Member: fmt.Sprintf("%d:%s:%s", veh.MetaData.Country, veh.VIN, hash)}
 	if _, err = vs.store.ZAdd(vinIndex, zVIN).Result(); err != nil {
-		return false, err
+		return notSynced, err
 	}
 	// Update the reg.nr index.
 	zReg := redis.Z{Score: 0, Member: fmt.Sprintf("%d:%s:%s", veh.MetaData.Country, veh.RegNr, hash)}
 	if _, err = vs.store.ZAdd(regIndex, zReg).Result(); err != nil {
+		return notSynced, err
+	}
+	return added, nil
+}
+
+// refresh copies the registration status, technical data, normalised fuel type, latest inspection and source from
+// "veh" to the "existing" vehicle and stores it, if either has changed. It returns a bool indicating whether the
+// vehicle was updated or not.
+func (vs *Store) refresh(existing, veh Vehicle) (bool, error) {
+	changed := false
+	// Keep what we have if the source doesn't know the status.
+	if veh.RegStatus != UnknownStatus && (existing.RegStatus != veh.RegStatus || !existing.RegStatusDate.Equal(veh.RegStatusDate)) {
+		existing.RegStatus = veh.RegStatus
+		existing.RegStatusDate = veh.RegStatusDate
+		changed = true
+	}
+	if veh.Tech != (TechData{}) && existing.Tech != veh.Tech {
+		existing.Tech = veh.Tech
+		changed = true
+	}
+	if veh.Fuel != UnknownFuel && existing.Fuel != veh.Fuel {
+		existing.Fuel = veh.Fuel
+		changed = true
+	}
+	if veh.LastInspection.Date.After(existing.LastInspection.Date) {
+		existing.LastInspection = veh.LastInspection
+		changed = true
+	}
+	// The provider that delivered the vehicle last is responsible for detecting its deregistration.
+	if veh.MetaData.Source != "" && existing.MetaData.Source != veh.MetaData.Source {
+		existing.MetaData.Source = veh.MetaData.Source
+		changed = true
+	}
+	if !changed {
+		return false, nil
+	}
+	existing.MetaData.LastUpdated = veh.MetaData.LastUpdated
+	if err := vs.updateVehicle(existing); err != nil {
 		return false, err
 	}
 	return true, nil
@@ -214,8 +348,15 @@ func (vs *Store) Enable(hash string) error {
 	if veh == (Vehicle{}) {
 		return ErrNoSuchVehicle
 	}
+	wasCounted := counts(veh)
 	veh.MetaData.Disabled = false
-	return vs.updateVehicle(veh)
+	if err = vs.updateVehicle(veh); err != nil {
+		return err
+	}
+	if wasCounted != counts(veh) {
+		return vs.countVehicle(veh, 1)
+	}
+	return nil
 }
 
 // Disable disables the vehicle with the given hash value, if it exists.
@@ -227,8 +368,15 @@ func (vs *Store) Disable(hash string) error {
 	if veh == (Vehicle{}) {
 		return ErrNoSuchVehicle
 	}
+	wasCounted := counts(veh)
 	veh.MetaData.Disabled = true
-	return vs.updateVehicle(veh)
+	if err = vs.updateVehicle(veh); err != nil {
+		return err
+	}
+	if wasCounted != counts(veh) {
+		return vs.countVehicle(veh, -1)
+	}
+	return nil
 }
 
 // remove removes the member with the given id from the sorted set index of the given name.
@@ -301,24 +449,80 @@ func (vs *Store) lookupVehicle(hash string, showDisabled bool, identifier, index
 	return veh, nil
 }
 
-// Clear clears out the entire vehicle store, including indexes but not the sync history.
+// Clear clears out the entire vehicle store, including indexes, inspections and the catalog but not the sync history.
 func (vs *Store) Clear() error {
-	keys := [...]string{vs.opts.SyncedFileString, vs.opts.VehicleMap, vs.opts.RegNrSortedSet, vs.opts.VINSortedSet}
+	if err := vs.clearInspections(); err != nil {
+		return err
+	}
+	if err := vs.clearCatalog(); err != nil {
+		return err
+	}
+	if err := vs.deleteMatching(vs.syncedKey("*")); err != nil {
+		return err
+	}
+	keys := [...]string{vs.opts.SyncedFileString, vs.opts.VehicleMap, vs.opts.RegNrSortedSet, vs.opts.VINSortedSet, vs.opts.SeenSet}
 	if _, err := vs.store.Del(keys[:]...).Result(); err != nil {
 		return err
 	}
 	return nil
 }
 
-// GetLastSynced returns the filename of the last file that was synchronised with the vehicle store.
-// It returns an empty string if there is no filename.
-func (vs *Store) GetLastSynced() (string, error) {
-	return vs.store.Get(vs.opts.SyncedFileString).Result()
+// deleteMatching deletes all keys that match the given pattern.
+func (vs *Store) deleteMatching(pattern string) error {
+	var (
+		keys []string
+		cur  uint64
+		err  error
+	)
+	for {
+		if keys, cur, err = vs.store.Scan(cur, pattern, 100).Result(); err != nil {
+			return err
+		}
+		if len(keys) > 0 {
+			if _, err = vs.store.Del(keys...).Result(); err != nil {
+				return err
+			}
+		}
+		if cur == 0 {
+			return nil
+		}
+	}
+}
+
+// syncedKey returns the key containing the filename of the last file synchronised from the given provider.
+func (vs *Store) syncedKey(provider string) string {
+	return vs.opts.SyncedFileString + ":" + provider
 }
 
-// SetLastSynced replaces the logged filename of the file that was last synchronised with the vehicle store.
-func (vs *Store) SetLastSynced(fname string) error {
-	if _, err := vs.store.Set(vs.opts.SyncedFileString, fname, 0).Result(); err != nil {
+// GetLastSynced returns the filename of the last file from the given provider that was synchronised with the vehicle
+// store. It returns an empty string if there is no filename.
+func (vs *Store) GetLastSynced(provider string) (string, error) {
+	return vs.store.Get(vs.syncedKey(provider)).Result()
+}
+
+// MigrateLastSynced hands the filename of stores that were synchronised before filenames were kept per provider over
+// to the given provider, which must be the one that synchronised it. The provider's own filename takes precedence if
+// it's already set. It reports whether there was a filename to migrate.
+func (vs *Store) MigrateLastSynced(provider string) (bool, error) {
+	renamed, err := vs.store.RenameNX(vs.opts.SyncedFileString, vs.syncedKey(provider)).Result()
+	if err != nil {
+		if isNoSuchKey(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !renamed {
+		if _, err = vs.store.Del(vs.opts.SyncedFileString).Result(); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// SetLastSynced replaces the logged filename of the file from the given provider that was last synchronised with the
+// vehicle store.
+func (vs *Store) SetLastSynced(provider, fname string) error {
+	if _, err := vs.store.Set(vs.syncedKey(provider), fname, 0).Result(); err != nil {
 		return err
 	}
 	return nil
@@ -356,8 +560,10 @@ func (vs *Store) CountLog() (int, error) {
 
 // QueryTo performs a query/search agsinst the store and streams the results to the provided reader.
 func (vs *Store) QueryTo(w io.Writer, q Query) error {
-	titles := []interface{}{"hash", "country", "ident", "reg nr", "vin", "brand", "model", "fuel type", "first reg date"}
-	fmt.Fprintf(w, "%q,%q,%q,%q,%q,%q,%q,%q,%q\n", titles...)
+	titles := []interface{}{"hash", "country", "ident", "reg nr", "vin", "brand", "model", "variant", "fuel type", "fuel", "first reg date", "reg status",
+		"engine power", "displacement", "curb weight", "total weight", "seats", "doors", "colour", "model year"}
+	csvFmt := strings.TrimSuffix(strings.Repeat("%q,", len(titles)), ",") + "\n"
+	fmt.Fprintf(w, csvFmt, titles...)
 	var (
 		batch, progress int64
 		keys            []string
@@ -368,11 +574,15 @@ func (vs *Store) QueryTo(w io.Writer, q Query) error {
 		ok              bool
 		veh             Vehicle
 	)
-	props = make([]interface{}, 10)
+	props = make([]interface{}, len(titles))
 	batch = 100
 	progress = 0
 	// Prepare some querying parameters.
 	pq := prepareQuery(q)
+	var tr Translator = enumNames{}
+	if q.Translator != nil {
+		tr = q.Translator
+	}
 	// Loop over cursors 100 entries at a time.
 	for {
 		if keys, cur, err = vs.store.HScan(vs.opts.VehicleMap, cur, "", batch).Result(); err != nil {
@@ -395,10 +605,10 @@ func (vs *Store) QueryTo(w io.Writer, q Query) error {
 			if !pq.validates(veh) {
 				continue
 			}
-			for i, prop := range veh.Slice() {
+			for i, prop := range veh.TranslatedSlice(tr) {
 				props[i] = prop
 			}
-			fmt.Fprintf(w, "%q,%q,%q,%q,%q,%q,%q,%q,%q,%q\n", props...)
+			fmt.Fprintf(w, csvFmt, props...)
 			progress++
 		}
 		if cur == 0 || (q.Limit > 0 && progress >= q.Limit) {
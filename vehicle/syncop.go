@@ -2,6 +2,7 @@ package vehicle
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 )
@@ -10,19 +11,37 @@ import (
 type SyncOpID int
 
 // syncOp represents a synchronization operation: when it started, how long it took, where it synced from
-// and how many vehicles were processed and synced, respectively.
+// and how many vehicles were processed, synced and marked as deregistered, respectively. Vehicles that were rejected
+// by data-quality rules are counted per rule. Vehicles that were added, reappeared or were marked as deregistered are
+// tallied, and the catalog is updated with the tally once the operation completes.
 type syncOp struct {
-	id        SyncOpID
-	started   time.Time
-	duration  time.Duration
-	source    string
-	processed int
-	synced    int
+	id                 SyncOpID
+	started            time.Time
+	duration           time.Duration
+	source             string
+	processed          int
+	synced             int
+	deregistered       int
+	detectDeregistered bool
+	rejected           map[string]int
+	tally              catalogTally
 }
 
 // String returns a string with some status information on the operation.
 func (op *syncOp) String() string {
-	return fmt.Sprintf("%s sync status - began: %s, duration: %s. Summary: synced %d of %d vehicles", strings.ToUpper(op.source), op.started.Format("2006-01-02T15:04:05"), op.duration.Truncate(time.Second), op.synced, op.processed)
+	status := fmt.Sprintf("%s sync status - began: %s, duration: %s. Summary: synced %d of %d vehicles, %d deregistered", strings.ToUpper(op.source), op.started.Format("2006-01-02T15:04:05"), op.duration.Truncate(time.Second), op.synced, op.processed, op.deregistered)
+	if len(op.rejected) == 0 {
+		return status
+	}
+	rules := make([]string, 0, len(op.rejected))
+	for rule := range op.rejected {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	for i, rule := range rules {
+		rules[i] = fmt.Sprintf("%s: %d", rule, op.rejected[rule])
+	}
+	return status + ". Rejected: " + strings.Join(rules, ", ")
 }
 
 // End sets the end time of the operation and calculates the duration.
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/mitchellh/hashstructure"
 )
@@ -35,7 +36,7 @@ func (reg RegCountry) String() string {
 // Type represents the overall type of vehicle, ie. car, trailer, van etc.
 type Type int
 
-// List of supported vehicle types.
+// List of supported vehicle types. These cover the vehicle categories used by DMR.
 const (
 	Unknown Type = iota
 	Car
@@ -43,6 +44,14 @@ const (
 	Van
 	Truck
 	Trailer
+	Motorcycle
+	Moped
+	Tractor
+	MotorisedTool
+	Camper
+	SemiTrailer
+	Caravan
+	Quadricycle
 )
 
 // String returns the string representation of the vehicle type.
@@ -58,6 +67,22 @@ func (t Type) String() string {
 		return "Truck"
 	case Trailer:
 		return "Trailer"
+	case Motorcycle:
+		return "Motorcycle"
+	case Moped:
+		return "Moped"
+	case Tractor:
+		return "Tractor"
+	case MotorisedTool:
+		return "MotorisedTool"
+	case Camper:
+		return "Camper"
+	case SemiTrailer:
+		return "SemiTrailer"
+	case Caravan:
+		return "Caravan"
+	case Quadricycle:
+		return "Quadricycle"
 	default:
 		return "Unknown"
 	}
@@ -77,33 +102,183 @@ func TypeFromString(str string) Type {
 		return Truck
 	case "trailer":
 		return Trailer
+	case "motorcycle":
+		return Motorcycle
+	case "moped":
+		return Moped
+	case "tractor":
+		return Tractor
+	case "motorisedtool":
+		return MotorisedTool
+	case "camper":
+		return Camper
+	case "semitrailer":
+		return SemiTrailer
+	case "caravan":
+		return Caravan
+	case "quadricycle":
+		return Quadricycle
 	default:
 		return Unknown
 	}
 }
 
+// TypeFromDanishName returns the Type that matches the given Danish vehicle category name (case insensitive match),
+// as used by DMR in KoeretoejArtNavn and by nrpla.de. Categories without an equivalent Type, ie. "Påhængsredskab",
+// and names that don't match result in Type.Unknown.
+func TypeFromDanishName(name string) Type {
+	switch strings.ToLower(strings.Join(strings.Fields(name), " ")) {
+	case "personbil":
+		return Car
+	case "bus", "stor personbil":
+		return Bus
+	case "varebil":
+		return Van
+	case "lastbil":
+		return Truck
+	case "påhængsvogn":
+		return Trailer
+	case "motorcykel":
+		return Motorcycle
+	case "knallert", "stor knallert", "lille knallert":
+		return Moped
+	case "traktor":
+		return Tractor
+	case "motorredskab":
+		return MotorisedTool
+	case "autocamper":
+		return Camper
+	case "sættevogn":
+		return SemiTrailer
+	case "campingvogn":
+		return Caravan
+	case "quadricykel", "firehjulet motorcykel":
+		return Quadricycle
+	default:
+		return Unknown
+	}
+}
+
+// DefaultTypes returns the vehicle types that are imported when nothing else has been configured.
+func DefaultTypes() []Type {
+	return []Type{Car, Bus, Van, Truck, Trailer}
+}
+
+// TypesFromStrings converts a list of vehicle type names into a list of Types. If the list is empty, the default
+// types are returned. Names that don't match a known type result in an error.
+func TypesFromStrings(names []string) ([]Type, error) {
+	if len(names) == 0 {
+		return DefaultTypes(), nil
+	}
+	types := make([]Type, 0, len(names))
+	for _, name := range names {
+		t := TypeFromString(name)
+		if t == Unknown && !strings.EqualFold(name, Unknown.String()) {
+			return nil, fmt.Errorf("unknown vehicle type: %s", name)
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+// RegStatus represents the registration status of a vehicle, as reported by the vehicle registry.
+type RegStatus int
+
+// List of supported registration statuses.
+const (
+	UnknownStatus RegStatus = iota
+	Registered
+	Deregistered
+	PreRegistered
+	Approved
+)
+
+// String returns the string representation of the registration status.
+func (rs RegStatus) String() string {
+	switch rs {
+	case Registered:
+		return "Registered"
+	case Deregistered:
+		return "Deregistered"
+	case PreRegistered:
+		return "PreRegistered"
+	case Approved:
+		return "Approved"
+	default:
+		return "Unknown"
+	}
+}
+
+// RegStatusFromString returns the RegStatus that matches the given string (case insensitive match).
+// The English names, the Danish names used by DMR and the Norwegian names used by Statens vegvesen are recognised. If there is no match,
+// RegStatus.UnknownStatus is returned.
+func RegStatusFromString(str string) RegStatus {
+	switch strings.ToLower(str) {
+	case "registered", "registreret", "registrert":
+		return Registered
+	case "deregistered", "afmeldt", "avregistrert", "vraket":
+		return Deregistered
+	case "preregistered", "forregistreret", "forhåndsregistrert":
+		return PreRegistered
+	case "approved", "godkendt", "godkjent":
+		return Approved
+	default:
+		return UnknownStatus
+	}
+}
+
 // Meta contains metadata for each vehicle.
+// Deregistered is set when the vehicle has disappeared from a complete data dump from its source, ie. it has been
+// scrapped or exported. DeregisteredAt contains the time when this was detected.
 type Meta struct {
-	Hash        uint64
-	Source      string
-	Country     RegCountry
-	Ident       uint64
-	LastUpdated time.Time
-	Disabled    bool
+	Hash           uint64
+	Source         string
+	Country        RegCountry
+	Ident          uint64
+	LastUpdated    time.Time
+	Disabled       bool
+	Deregistered   bool
+	DeregisteredAt time.Time
+}
+
+// TechData contains technical data for a vehicle. Values that are unknown are left as zero values.
+// EnginePower is in kW, Displacement in cm3 and weights in kg.
+type TechData struct {
+	EnginePower  float64
+	Displacement int
+	CurbWeight   int
+	TotalWeight  int
+	Seats        int
+	Doors        int
+	Colour       string
+	ModelYear    int
 }
 
 // Vehicle contains the core vehicle data that Autobot manages.
 // As vehicles are persisted in Redis / Google Memory Store, they should not contain pointers.
+// The registration status changes during the lifetime of a vehicle, so it's not part of the hash. Neither is the
+// technical data, which was added after the hash was introduced and would otherwise change the hash of every vehicle.
+// LastInspection contains the latest inspection known from the source; the full timeline is kept in the store.
+// The brand, model and variant ids are assigned by the Catalogue and are zero for names that are not catalogued.
+// FuelType contains the fuel type as delivered by the source, while Fuel contains the normalised fuel type.
 type Vehicle struct {
-	MetaData     Meta `hash:"ignore"`
-	Type         Type
-	RegNr        string
-	VIN          string
-	Brand        string
-	Model        string
-	FuelType     string
-	Variant      string
-	FirstRegDate time.Time
+	MetaData       Meta `hash:"ignore"`
+	Type           Type
+	RegNr          string
+	VIN            string
+	Brand          string
+	Model          string
+	FuelType       string
+	Variant        string
+	FirstRegDate   time.Time
+	RegStatus      RegStatus  `hash:"ignore"`
+	RegStatusDate  time.Time  `hash:"ignore"`
+	Tech           TechData   `hash:"ignore"`
+	LastInspection Inspection `hash:"ignore"`
+	BrandID        int        `hash:"ignore"`
+	ModelID        int        `hash:"ignore"`
+	VariantID      int        `hash:"ignore"`
+	Fuel           FuelType   `hash:"ignore"`
 }
 
 // Marshal converts the given Vehicle to a string using JSON encoding.
@@ -145,29 +320,98 @@ func (v Vehicle) String() string {
 	return v.FlexString("", " ")
 }
 
+// Translator translates enumerated vehicle values, and inspection terms as delivered by the data sources, into human
+// readable names, ie. for a specific language.
+type Translator interface {
+	Type(Type) string
+	Fuel(FuelType) string
+	RegStatus(RegStatus) string
+	InspectionTerm(string) string
+}
+
+// enumNames is the default Translator, which simply uses the enum names and inspection terms as is.
+type enumNames struct{}
+
+func (enumNames) Type(t Type) string                { return t.String() }
+func (enumNames) Fuel(ft FuelType) string           { return ft.String() }
+func (enumNames) RegStatus(rs RegStatus) string     { return rs.String() }
+func (enumNames) InspectionTerm(term string) string { return term }
+
 // FlexString returns a stringified multi-line representation of the Vehicle data structure.
 func (v Vehicle) FlexString(lb, leftPad string) string {
+	return v.TranslatedString(lb, leftPad, enumNames{})
+}
+
+// TranslatedString works like FlexString, but uses the given Translator for enumerated values.
+func (v Vehicle) TranslatedString(lb, leftPad string, tr Translator) string {
 	var txt strings.Builder
 	fmt.Fprintf(&txt, "#%d (%s)%s", v.MetaData.Hash, DisabledAsString(v.MetaData.Disabled), lb)
 	fmt.Fprintf(&txt, "%sCountry: %s%s", leftPad, v.MetaData.Country.String(), lb)
 	fmt.Fprintf(&txt, "%sIdent: %d%s", leftPad, v.MetaData.Ident, lb)
+	fmt.Fprintf(&txt, "%sType: %s%s", leftPad, tr.Type(v.Type), lb)
 	fmt.Fprintf(&txt, "%sRegNr: %s%s", leftPad, v.RegNr, lb)
 	fmt.Fprintf(&txt, "%sVIN: %s%s", leftPad, v.VIN, lb)
 	fmt.Fprintf(&txt, "%sBrand: %s%s", leftPad, v.Brand, lb)
 	fmt.Fprintf(&txt, "%sModel: %s%s", leftPad, v.Model, lb)
 	fmt.Fprintf(&txt, "%sVariant: %s%s", leftPad, v.Variant, lb)
-	fmt.Fprintf(&txt, "%sFuelType: %s%s", leftPad, v.FuelType, lb)
+	fmt.Fprintf(&txt, "%sFuelType: %s (%s)%s", leftPad, tr.Fuel(v.NormalisedFuel()), v.FuelType, lb)
 	fmt.Fprintf(&txt, "%sRegDate: %s%s", leftPad, v.FirstRegDate.Format("2006-01-02"), lb)
+	fmt.Fprintf(&txt, "%sRegStatus: %s%s", leftPad, tr.RegStatus(v.RegStatus), lb)
+	if !v.RegStatusDate.IsZero() {
+		fmt.Fprintf(&txt, "%sRegStatusDate: %s%s", leftPad, v.RegStatusDate.Format("2006-01-02"), lb)
+	}
+	fmt.Fprintf(&txt, "%sEnginePower: %.1f kW%s", leftPad, v.Tech.EnginePower, lb)
+	fmt.Fprintf(&txt, "%sDisplacement: %d cm3%s", leftPad, v.Tech.Displacement, lb)
+	fmt.Fprintf(&txt, "%sCurbWeight: %d kg%s", leftPad, v.Tech.CurbWeight, lb)
+	fmt.Fprintf(&txt, "%sTotalWeight: %d kg%s", leftPad, v.Tech.TotalWeight, lb)
+	fmt.Fprintf(&txt, "%sSeats: %d%s", leftPad, v.Tech.Seats, lb)
+	fmt.Fprintf(&txt, "%sDoors: %d%s", leftPad, v.Tech.Doors, lb)
+	fmt.Fprintf(&txt, "%sColour: %s%s", leftPad, v.Tech.Colour, lb)
+	fmt.Fprintf(&txt, "%sModelYear: %d%s", leftPad, v.Tech.ModelYear, lb)
+	if !v.LastInspection.IsZero() {
+		fmt.Fprintf(&txt, "%sLastInspection: %s%s", leftPad, v.LastInspection.TranslatedString(tr), lb)
+	}
+	if v.MetaData.Deregistered {
+		fmt.Fprintf(&txt, "%sDeregistered: %s%s", leftPad, v.MetaData.DeregisteredAt.Format("2006-01-02"), lb)
+	}
 	return txt.String()
 }
 
+// NormalisedFuel returns the normalised fuel type of the vehicle. Vehicles that were stored before fuel types were
+// normalised only have the raw fuel type, which is normalised on the fly.
+func (v Vehicle) NormalisedFuel() FuelType {
+	if v.Fuel == UnknownFuel {
+		return FuelTypeFromString(v.FuelType)
+	}
+	return v.Fuel
+}
+
+// IsDeregistered reports whether the vehicle is deregistered, either according to the vehicle registry,
+// or because it has disappeared from the data source.
+func (v Vehicle) IsDeregistered() bool {
+	return v.RegStatus == Deregistered || v.MetaData.Deregistered
+}
+
 // Slice returns most properties from Vehicle as a slice of strings, intended for use in CSV conversions.
-func (v Vehicle) Slice() [10]string {
+func (v Vehicle) Slice() [20]string {
+	return v.TranslatedSlice(enumNames{})
+}
+
+// TranslatedSlice works like Slice, but uses the given Translator for enumerated values.
+func (v Vehicle) TranslatedSlice(tr Translator) [20]string {
 	hash := strconv.FormatUint(v.MetaData.Hash, 10)
 	country := v.MetaData.Country.String()
 	ident := strconv.FormatUint(v.MetaData.Ident, 10)
 	firstReg := v.FirstRegDate.Format("2006-01-02")
-	props := [10]string{hash, country, ident, v.RegNr, v.VIN, v.Brand, v.Model, v.Variant, v.FuelType, firstReg}
+	power := strconv.FormatFloat(v.Tech.EnginePower, 'f', -1, 64)
+	displacement := strconv.Itoa(v.Tech.Displacement)
+	curbWeight := strconv.Itoa(v.Tech.CurbWeight)
+	totalWeight := strconv.Itoa(v.Tech.TotalWeight)
+	seats := strconv.Itoa(v.Tech.Seats)
+	doors := strconv.Itoa(v.Tech.Doors)
+	modelYear := strconv.Itoa(v.Tech.ModelYear)
+	props := [20]string{hash, country, ident, v.RegNr, v.VIN, v.Brand, v.Model, v.Variant, v.FuelType, tr.Fuel(v.NormalisedFuel()), firstReg, tr.RegStatus(v.RegStatus),
+		power, displacement, curbWeight, totalWeight, seats, doors, v.Tech.Colour, modelYear}
 	return props
 }
 
@@ -177,12 +421,47 @@ func PrettyBrandName(brand string) string {
 	if len(brand) <= 3 {
 		return strings.ToUpper(brand)
 	}
-	return strings.Title(strings.ToLower(brand))
+	return titleCase(brand)
 }
 
 // PrettyFuelType normalizes fuel-type by capitalizing the first letter only.
 func PrettyFuelType(ft string) string {
-	return strings.Title(strings.ToLower(ft))
+	return titleCase(ft)
+}
+
+// PrettyColour normalizes a colour name by capitalizing the first letter of each word, ie. "Lysegrå" or "Rød".
+func PrettyColour(colour string) string {
+	return titleCase(colour)
+}
+
+// titleCase lowercases the given string, except for the first letter of each word, which is title-cased. It replaces
+// the deprecated strings.Title, and treats the same characters as word separators, so names are cased as before.
+func titleCase(str string) string {
+	prev := ' '
+	return strings.Map(func(r rune) rune {
+		sep := isSeparator(prev)
+		prev = r
+		if sep {
+			return unicode.ToTitle(r)
+		}
+		return unicode.ToLower(r)
+	}, str)
+}
+
+// isSeparator reports whether the given rune separates words: ASCII characters other than letters, digits and
+// underscores, and spaces.
+func isSeparator(r rune) bool {
+	if r <= 0x7F {
+		switch {
+		case '0' <= r && r <= '9', 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', r == '_':
+			return false
+		}
+		return true
+	}
+	if unicode.IsLetter(r) || unicode.IsDigit(r) {
+		return false
+	}
+	return unicode.IsSpace(r)
 }
 
 // HashAsKey converts the given hash value into a string that can be used as key in the vehicle store.
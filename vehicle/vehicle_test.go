@@ -1,8 +1,12 @@
 package vehicle
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/mkock/autobot/config"
 )
 
 func TestRegCountryFromString(t *testing.T) {
@@ -35,7 +39,7 @@ func TestRegCountryToString(t *testing.T) {
 func TestGenHash(t *testing.T) {
 	var err error
 	v := Vehicle{}
-	v.MetaData = Meta{0, "A Source", DK, 0, time.Now(), false}
+	v.MetaData = Meta{Hash: 0, Source: "A Source", Country: DK, Ident: 0, LastUpdated: time.Now(), Disabled: false}
 	if err = v.GenHash(); err != nil {
 		t.Fatal(err)
 	}
@@ -55,10 +59,12 @@ func TestGenHash(t *testing.T) {
 
 func TestPrettyBrandName(t *testing.T) {
 	cases := map[string]string{
-		"bmw":     "BMW",
-		"PEUGEOT": "Peugeot",
-		"ds":      "DS",
-		"MINI":    "Mini",
+		"bmw":           "BMW",
+		"PEUGEOT":       "Peugeot",
+		"ds":            "DS",
+		"MINI":          "Mini",
+		"MERCEDES-BENZ": "Mercedes-Benz",
+		"ROLLS ROYCE":   "Rolls Royce",
 	}
 	var actual string
 	for in, expected := range cases {
@@ -83,6 +89,21 @@ func TestPrettyFuelType(t *testing.T) {
 	}
 }
 
+func TestPrettyColour(t *testing.T) {
+	cases := map[string]string{
+		"HVID":     "Hvid",
+		"lysegrå":  "Lysegrå",
+		"MØRK BLÅ": "Mørk Blå",
+	}
+	var actual string
+	for in, expected := range cases {
+		actual = PrettyColour(in)
+		if actual != expected {
+			t.Fatalf("Expected %v but got %v", expected, actual)
+		}
+	}
+}
+
 func TestQueryValidates(t *testing.T) {
 	vehicles := map[string]Vehicle{
 		"FordMondeo": Vehicle{
@@ -208,3 +229,332 @@ func TestQueryValidatesEmptyCond(t *testing.T) {
 		}
 	}
 }
+
+func TestExceedsThreshold(t *testing.T) {
+	cases := []struct {
+		candidates, total int
+		threshold         float64
+		expected          bool
+	}{
+		{0, 100, 0.05, false},
+		{5, 100, 0.05, false},
+		{6, 100, 0.05, true},
+		{0, 0, 0.05, false},
+		{1, 0, 0.05, true},
+	}
+	var actual bool
+	for _, c := range cases {
+		actual = exceedsThreshold(c.candidates, c.total, c.threshold)
+		if actual != c.expected {
+			t.Fatalf("Expected %v but got %v for %d of %d", c.expected, actual, c.candidates, c.total)
+		}
+	}
+}
+
+func TestRegStatusFromString(t *testing.T) {
+	cases := map[string]RegStatus{
+		"Registreret":    Registered,
+		"AFMELDT":        Deregistered,
+		"Forregistreret": PreRegistered,
+		"registered":     Registered,
+		"":               UnknownStatus,
+	}
+	var actual RegStatus
+	for in, expected := range cases {
+		actual = RegStatusFromString(in)
+		if actual != expected {
+			t.Fatalf("Expected %v but got %v", expected, actual)
+		}
+	}
+}
+
+func TestRegStatusIgnoredByHash(t *testing.T) {
+	v := Vehicle{Type: Car, RegNr: "AB12345", VIN: "WVWZZZ1JZXW000001", RegStatus: Registered}
+	if err := v.GenHash(); err != nil {
+		t.Fatal(err)
+	}
+	expected := v.MetaData.Hash
+	v.RegStatus = Deregistered
+	v.RegStatusDate = time.Now()
+	if err := v.GenHash(); err != nil {
+		t.Fatal(err)
+	}
+	if v.MetaData.Hash != expected {
+		t.Fatal("Expected hash to be unaffected by changes to registration status")
+	}
+}
+
+func TestTechDataIgnoredByHash(t *testing.T) {
+	v := Vehicle{Type: Car, RegNr: "AB12345", VIN: "WVWZZZ1JZXW000001"}
+	if err := v.GenHash(); err != nil {
+		t.Fatal(err)
+	}
+	expected := v.MetaData.Hash
+	v.Tech = TechData{EnginePower: 85, Displacement: 1598, CurbWeight: 1280, Seats: 5, Doors: 5, Colour: "Blå", ModelYear: 2015}
+	if err := v.GenHash(); err != nil {
+		t.Fatal(err)
+	}
+	if v.MetaData.Hash != expected {
+		t.Fatal("Expected hash to be unaffected by technical data")
+	}
+}
+
+func TestLatestOdometer(t *testing.T) {
+	first := time.Date(2015, 3, 1, 0, 0, 0, 0, time.UTC)
+	second := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	timeline := InspectionTimeline{
+		{Date: first, Type: "Periodisk syn", Result: "Godkendt", Odometer: 60000},
+		{Date: second, Type: "Periodisk syn", Result: "Godkendt", Odometer: 95000},
+		{Date: second.AddDate(0, 1, 0), Type: "Omsyn", Result: "Godkendt"},
+	}
+	odo, date := timeline.LatestOdometer()
+	if odo != 95000 || !date.Equal(second) {
+		t.Fatalf("Expected %v but got %v (%v)", 95000, odo, date)
+	}
+	odo, date = InspectionTimeline{}.LatestOdometer()
+	if odo != 0 || !date.IsZero() {
+		t.Fatalf("Expected no odometer value but got %v (%v)", odo, date)
+	}
+}
+
+func TestTypeRoundTrip(t *testing.T) {
+	for vt := Unknown; vt <= Quadricycle; vt++ {
+		actual := TypeFromString(vt.String())
+		if actual != vt {
+			t.Fatalf("Expected %v but got %v", vt, actual)
+		}
+	}
+}
+
+func TestTypesFromStrings(t *testing.T) {
+	types, err := TypesFromStrings(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != len(DefaultTypes()) {
+		t.Fatalf("Expected %v but got %v", DefaultTypes(), types)
+	}
+	types, err = TypesFromStrings([]string{"car", "Motorcycle", "unknown"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Type{Car, Motorcycle, Unknown}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Fatalf("Expected %v but got %v", expected[i], types[i])
+		}
+	}
+	if _, err = TypesFromStrings([]string{"Spaceship"}); err == nil {
+		t.Fatal("Expected an error for an unknown vehicle type")
+	}
+}
+
+func TestTypeFromDanishName(t *testing.T) {
+	cases := map[string]Type{
+		"Personbil":             Car,
+		"Stor personbil":        Bus,
+		"Bus":                   Bus,
+		"Varebil":               Van,
+		"LASTBIL":               Truck,
+		"Påhængsvogn":           Trailer,
+		"Motorcykel":            Motorcycle,
+		"Stor  knallert":        Moped,
+		"Lille knallert":        Moped,
+		"Traktor":               Tractor,
+		"Motorredskab":          MotorisedTool,
+		"Autocamper":            Camper,
+		"Sættevogn":             SemiTrailer,
+		"Campingvogn":           Caravan,
+		"Firehjulet motorcykel": Quadricycle,
+		"Påhængsredskab":        Unknown,
+		"":                      Unknown,
+	}
+	for name, expected := range cases {
+		if actual := TypeFromDanishName(name); actual != expected {
+			t.Fatalf("Expected %v but got %v for %q", expected, actual, name)
+		}
+	}
+}
+
+func TestVINValidation(t *testing.T) {
+	cases := map[string][2]bool{
+		"1M8GDM9AXKP042788": {true, true},   // Well-formed with a valid check digit.
+		"1M8GDM9A1KP042788": {true, false},  // Well-formed with an invalid check digit.
+		"WVWZZZ1JZXW00000I": {false, false}, // Contains the letter I.
+		"WVWZZZ1JZ":         {false, false}, // Too short.
+	}
+	for vin, expected := range cases {
+		if actual := WellFormedVIN(vin); actual != expected[0] {
+			t.Fatalf("Expected %v but got %v for %s", expected[0], actual, vin)
+		}
+		if actual := ValidVINCheckDigit(vin); actual != expected[1] {
+			t.Fatalf("Expected %v but got %v for %s", expected[1], actual, vin)
+		}
+	}
+}
+
+func TestRuleSetReject(t *testing.T) {
+	rs := NewRuleSet(config.RulesConfig{
+		RejectMissingVIN:    true,
+		RejectFutureRegDate: true,
+		BrandBlocklist:      []string{"Unknown Brand"},
+	})
+	cases := map[string]Vehicle{
+		"":                {VIN: "WVWZZZ1JZXW000001", Brand: "Volkswagen", FirstRegDate: time.Now().AddDate(-1, 0, 0)},
+		RuleMissingVIN:    {Brand: "Volkswagen", FirstRegDate: time.Now().AddDate(-1, 0, 0)},
+		RuleFutureRegDate: {VIN: "WVWZZZ1JZXW000001", Brand: "Volkswagen", FirstRegDate: time.Now().AddDate(1, 0, 0)},
+		RuleBlockedBrand:  {VIN: "WVWZZZ1JZXW000001", Brand: "UNKNOWN-BRAND", FirstRegDate: time.Now().AddDate(-1, 0, 0)},
+	}
+	for expected, veh := range cases {
+		if actual := rs.Reject(veh); actual != expected {
+			t.Fatalf("Expected %q but got %q", expected, actual)
+		}
+	}
+}
+
+func TestCatalogueNormalise(t *testing.T) {
+	cat := NewCatalogue()
+	cat.AddBrand(CatalogueBrand{ID: 1, Name: "Mercedes-Benz", Aliases: []string{"Mercedes"}})
+	cat.AddBrand(CatalogueBrand{ID: 2, Name: "Volkswagen", Aliases: []string{"VW"}, Model: []CatalogueModel{
+		{ID: 10, Name: "Golf", Variant: []CatalogueVariant{{Name: "GTI", Aliases: []string{"2.0 TSI GTI"}}}},
+	}})
+	if err := cat.AddModel("MERCEDES BENZ", CatalogueModel{Name: "C-Klasse", Aliases: []string{"C 220"}}); err != nil {
+		t.Fatal(err)
+	}
+	modelID, variantID := derivedID("Mercedes-Benz", "C-Klasse"), derivedID("Volkswagen", "Golf", "GTI")
+	cases := []struct {
+		in, expected Vehicle
+	}{
+		{Vehicle{Brand: "MERCEDES-BENZ", Model: "C 220"}, Vehicle{Brand: "Mercedes-Benz", Model: "C-Klasse", BrandID: 1, ModelID: modelID}},
+		{Vehicle{Brand: "vw", Model: "GOLF", Variant: "2.0 tsi gti"}, Vehicle{Brand: "Volkswagen", Model: "Golf", Variant: "GTI", BrandID: 2, ModelID: 10, VariantID: variantID}},
+		{Vehicle{Brand: "PEUGEOT", Model: "208"}, Vehicle{Brand: "Peugeot", Model: "208"}},
+	}
+	for _, c := range cases {
+		actual := c.in
+		cat.Normalise(&actual)
+		if actual != c.expected {
+			t.Fatalf("Expected %v but got %v", c.expected, actual)
+		}
+	}
+	if err := cat.AddModel("Tesla", CatalogueModel{Name: "Model 3"}); err == nil {
+		t.Fatal("Expected an error when adding a model to an unknown brand")
+	}
+}
+
+func TestCatalogueSave(t *testing.T) {
+	fname := filepath.Join(t.TempDir(), "catalogue.toml")
+	if err := ioutil.WriteFile(fname, []byte("[[Brand]]\nName = \"Skoda\"\nAliases = [\"ŠKODA\"]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cat, err := LoadCatalogue(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = cat.AddModel("SKODA", CatalogueModel{Name: "Octavia"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = cat.AddVariant("Skoda", "OCTAVIA", CatalogueVariant{Name: "RS", Aliases: []string{"2.0 TSI RS"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = cat.Save(); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := LoadCatalogue(fname)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Vehicle{Brand: "Skoda", Model: "Octavia", Variant: "RS", BrandID: derivedID("Skoda"), ModelID: derivedID("Skoda", "Octavia"), VariantID: derivedID("Skoda", "Octavia", "RS")}
+	actual := Vehicle{Brand: "ŠKODA", Model: "octavia", Variant: "2.0 tsi rs"}
+	saved.Normalise(&actual)
+	if actual != expected {
+		t.Fatalf("Expected %v but got %v", expected, actual)
+	}
+}
+
+func TestCatalogTally(t *testing.T) {
+	tally := make(catalogTally)
+	tally.add(Vehicle{Brand: "Ford", Model: "Fiesta", Variant: "1.0 EcoBoost", FirstRegDate: time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)})
+	tally.add(Vehicle{Brand: "Ford", Model: "Fiesta", FirstRegDate: time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC)})
+	tally.add(Vehicle{Brand: "Ford", Model: "Mondeo", FirstRegDate: time.Date(2011, 1, 1, 0, 0, 0, 0, time.UTC)})
+	expected := CatalogEntry{Name: "Ford", Count: 3, FirstYear: 2009, LastYear: 2014}
+	if actual := tally[brandsKey()]["ford"]; actual != expected {
+		t.Fatalf("Expected %v but got %v", expected, actual)
+	}
+	expected = CatalogEntry{Name: "Fiesta", Count: 2, FirstYear: 2009, LastYear: 2014}
+	if actual := tally[modelsKey("FORD")]["fiesta"]; actual != expected {
+		t.Fatalf("Expected %v but got %v", expected, actual)
+	}
+	if actual := len(tally[variantsKey("Ford", "Fiesta")]); actual != 1 {
+		t.Fatalf("Expected %v but got %v", 1, actual)
+	}
+}
+
+func TestFuelTypeFromString(t *testing.T) {
+	cases := map[string]FuelType{
+		"Benzin":         Petrol,
+		"PETROL":         Petrol,
+		"El":             Electric,
+		"Electricity":    Electric,
+		"Plug-in hybrid": PluginHybrid,
+		"Brint":          Hydrogen,
+		"F-Gas":          Gas,
+		"Petroleum":      OtherFuel,
+		"":               UnknownFuel,
+	}
+	var actual FuelType
+	for in, expected := range cases {
+		actual = FuelTypeFromString(in)
+		if actual != expected {
+			t.Fatalf("Expected %v but got %v for %q", expected, actual, in)
+		}
+	}
+	for ft := UnknownFuel; ft <= OtherFuel; ft++ {
+		if actual = FuelTypeFromString(ft.String()); actual != ft {
+			t.Fatalf("Expected %v but got %v", ft, actual)
+		}
+	}
+}
+
+func TestQueryValidatesFuel(t *testing.T) {
+	dmrCar := Vehicle{Type: Car, Brand: "Ford", FuelType: "Benzin", Fuel: Petrol}
+	apiCar := Vehicle{Type: Car, Brand: "Ford", FuelType: "Petrol", Fuel: Petrol}
+	oddCar := Vehicle{Type: Car, Brand: "Ford", FuelType: "Petroleum", Fuel: OtherFuel}
+	oldCar := Vehicle{Type: Car, Brand: "Ford", FuelType: "Benzin"} // Stored before fuel types were normalised.
+	pq := prepareQuery(Query{FuelType: "petrol"})
+	if !pq.validates(dmrCar) || !pq.validates(apiCar) || !pq.validates(oldCar) || pq.validates(oddCar) {
+		t.Fatal("Expected normalised fuel type to match DMR, API and previously stored vehicles only")
+	}
+	pq = prepareQuery(Query{FuelType: "petroleum"})
+	if pq.validates(dmrCar) || !pq.validates(oddCar) {
+		t.Fatal("Expected unrecognised fuel type to match the raw fuel type")
+	}
+}
+
+func TestInspectionKey(t *testing.T) {
+	vs := NewStore(config.MemStoreConfig{}, config.SyncConfig{InspectionSortedSet: "insp"}, ioutil.Discard)
+	veh := Vehicle{MetaData: Meta{Hash: 1, Country: DK, Ident: 42}, RegNr: "AB12345"}
+	renamed := veh
+	renamed.MetaData.Hash, renamed.RegNr = 2, "CD67890"
+	if vs.inspectionKey(veh) != vs.inspectionKey(renamed) {
+		t.Fatalf("Expected %v but got %v", vs.inspectionKey(veh), vs.inspectionKey(renamed))
+	}
+	if key := vs.inspectionKey(Vehicle{MetaData: Meta{Hash: 1, Country: DK}}); key != "insp:"+HashAsKey(1) {
+		t.Fatalf("Expected %v but got %v", "insp:"+HashAsKey(1), key)
+	}
+}
+
+func TestCatalogTallyRemove(t *testing.T) {
+	tally := make(catalogTally)
+	veh := Vehicle{Brand: "Ford", Model: "Fiesta", FirstRegDate: time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC)}
+	tally.add(veh)
+	tally.add(veh)
+	tally.remove(veh)
+	expected := CatalogEntry{Name: "Fiesta", Count: 1, FirstYear: 2014, LastYear: 2014}
+	if actual := tally[modelsKey("Ford")]["fiesta"]; actual != expected {
+		t.Fatalf("Expected %v but got %v", expected, actual)
+	}
+	veh.MetaData.Deregistered = true
+	if counts(veh) {
+		t.Fatalf("Expected %v but got %v", false, true)
+	}
+}
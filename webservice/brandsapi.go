@@ -0,0 +1,71 @@
+package webservice
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mkock/autobot/vehicle"
+)
+
+// APICatalogEntry is the API representation of vehicle.CatalogEntry.
+type APICatalogEntry struct {
+	Name      string `json:"name"`
+	Count     int    `json:"count"`
+	FirstYear int    `json:"firstYear"`
+	LastYear  int    `json:"lastYear"`
+}
+
+// catalogToAPIType converts a list of vehicle.CatalogEntry into a list of the local APICatalogEntry.
+func catalogToAPIType(entries []vehicle.CatalogEntry) []APICatalogEntry {
+	apiEntries := make([]APICatalogEntry, 0, len(entries))
+	for _, entry := range entries {
+		apiEntries = append(apiEntries, APICatalogEntry(entry))
+	}
+	return apiEntries
+}
+
+// handleBrands serves the brands, models and variants of the vehicles in the store, with vehicle counts. Supported
+// paths: /brands, /brands/{brand}/models and /brands/{brand}/models/{model}/variants.
+func (srv *WebServer) handleBrands(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.EscapedPath(), "/"), "/"), "/")
+	for i, part := range parts {
+		var err error
+		if parts[i], err = url.PathUnescape(part); err != nil {
+			srv.JSONError(w, APIError{http.StatusBadRequest, errBrands, err.Error()})
+			return
+		}
+	}
+	var (
+		entries []vehicle.CatalogEntry
+		err     error
+	)
+	switch {
+	case len(parts) == 1 && parts[0] == "brands":
+		entries, err = srv.store.CatalogBrands()
+	case len(parts) == 3 && parts[0] == "brands" && parts[2] == "models":
+		entries, err = srv.store.CatalogModels(parts[1])
+	case len(parts) == 5 && parts[0] == "brands" && parts[2] == "models" && parts[4] == "variants":
+		entries, err = srv.store.CatalogVariants(parts[1], parts[3])
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		srv.JSONError(w, APIError{http.StatusInternalServerError, errBrands, err.Error()})
+		return
+	}
+	bytes, err := json.Marshal(catalogToAPIType(entries))
+	if err != nil {
+		srv.JSONError(w, APIError{http.StatusInternalServerError, errJSONEncoding, err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(bytes)
+}
@@ -0,0 +1,95 @@
+package webservice
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mkock/autobot/locale"
+	"github.com/mkock/autobot/vehicle"
+)
+
+// APIInspection is the API representation of vehicle.Inspection.
+type APIInspection struct {
+	Date     string `json:"date"`
+	Type     string `json:"type"`
+	Result   string `json:"result"`
+	Odometer int    `json:"odometer"`
+}
+
+// APIInspections contains the inspection timeline of a single vehicle together with its latest known odometer value.
+type APIInspections struct {
+	Hash               string          `json:"hash"`
+	LatestOdometer     int             `json:"latestOdometer"`
+	LatestOdometerDate string          `json:"latestOdometerDate,omitempty"`
+	Inspections        []APIInspection `json:"inspections"`
+}
+
+// timelineToAPIType converts a vehicle.InspectionTimeline into the local APIInspections, with inspection types and
+// results translated into the given language.
+func timelineToAPIType(hash string, timeline vehicle.InspectionTimeline, lang locale.Lang) APIInspections {
+	inspections := make([]APIInspection, 0, len(timeline))
+	for _, insp := range timeline {
+		inspections = append(inspections, APIInspection{insp.Date.Format(dateFmt), lang.InspectionTerm(insp.Type), lang.InspectionTerm(insp.Result), insp.Odometer})
+	}
+	var odoDate string
+	odo, date := timeline.LatestOdometer()
+	if !date.IsZero() {
+		odoDate = date.Format(dateFmt)
+	}
+	return APIInspections{hash, odo, odoDate, inspections}
+}
+
+// handleInspections returns the inspection timeline for a cached vehicle, looked up by hash value or a combination
+// of country and VIN or registration number. Direct lookups are not performed.
+func (srv *WebServer) handleInspections(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	country := r.URL.Query().Get("country")
+	hash := r.URL.Query().Get("hash")
+	regNr := r.URL.Query().Get("regnr")
+	vin := r.URL.Query().Get("vin")
+	if regNr == "" && vin == "" && hash == "" {
+		srv.JSONError(w, APIError{http.StatusBadRequest, errInspections, "Missing query parameter 'hash', 'regnr' or 'vin'"})
+		return
+	}
+	if country == "" && hash == "" {
+		srv.JSONError(w, APIError{http.StatusBadRequest, errInspections, "Missing query parameter 'country'"})
+		return
+	}
+	var (
+		veh vehicle.Vehicle
+		err error
+	)
+	regCountry := vehicle.RegCountryFromString(country)
+	if hash != "" {
+		veh, err = srv.store.LookupByHash(hash)
+	} else if regNr != "" {
+		veh, err = srv.store.LookupByRegNr(regCountry, regNr, false)
+	} else {
+		veh, err = srv.store.LookupByVIN(regCountry, vin, false)
+	}
+	if err != nil {
+		srv.JSONError(w, APIError{http.StatusInternalServerError, errInspections, err.Error()})
+		return
+	}
+	if veh == (vehicle.Vehicle{}) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	hash = vehicle.HashAsKey(veh.MetaData.Hash)
+	timeline, err := srv.store.Inspections(veh)
+	if err != nil {
+		srv.JSONError(w, APIError{http.StatusInternalServerError, errInspections, err.Error()})
+		return
+	}
+	bytes, err := json.Marshal(timelineToAPIType(hash, timeline, requestLang(r)))
+	if err != nil {
+		srv.JSONError(w, APIError{http.StatusInternalServerError, errMarshalling, err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(bytes)
+}
@@ -6,32 +6,58 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/mkock/autobot/extlookup"
+	"github.com/mkock/autobot/locale"
 	"github.com/mkock/autobot/vehicle"
 )
 
+// APITechData is the API representation of vehicle.TechData.
+type APITechData struct {
+	EnginePower  float64 `json:"enginePower,omitempty"`
+	Displacement int     `json:"displacement,omitempty"`
+	CurbWeight   int     `json:"curbWeight,omitempty"`
+	TotalWeight  int     `json:"totalWeight,omitempty"`
+	Seats        int     `json:"seats,omitempty"`
+	Doors        int     `json:"doors,omitempty"`
+	Colour       string  `json:"colour,omitempty"`
+	ModelYear    int     `json:"modelYear,omitempty"`
+}
+
 // APIVehicle is the API representation of Vehicle. It has a JSON representation.
 // Some fields that are only for internal use, are left out, and others are converted into something more readable.
 type APIVehicle struct {
-	Hash         string `json:"hash"`
-	Country      string `json:"country"`
-	Type         string `json:"type"`
-	RegNr        string `json:"regNr"`
-	VIN          string `json:"vin"`
-	Brand        string `json:"brand"`
-	Model        string `json:"model"`
-	Variant      string `json:"variant"`
-	FuelType     string `json:"fuelType"`
-	FirstRegDate string `json:"firstRegDate"`
-	FromCache    bool   `json:"fromCache"`
+	Hash          string      `json:"hash"`
+	Country       string      `json:"country"`
+	Type          string      `json:"type"`
+	RegNr         string      `json:"regNr"`
+	VIN           string      `json:"vin"`
+	Brand         string      `json:"brand"`
+	Model         string      `json:"model"`
+	Variant       string      `json:"variant"`
+	FuelType      string      `json:"fuelType"`
+	Fuel          string      `json:"fuel"`
+	FirstRegDate  string      `json:"firstRegDate"`
+	RegStatus     string      `json:"regStatus"`
+	RegStatusDate string      `json:"regStatusDate,omitempty"`
+	Tech          APITechData `json:"tech"`
+	Deregistered  bool        `json:"deregistered"`
+	FromCache     bool        `json:"fromCache"`
+	LookupService string      `json:"lookupService,omitempty"`
 }
 
 // vehicleToAPIType converts a vehicle.Vehicle into the local APIVehicle, which is used for the http request/response.
-func vehicleToAPIType(veh vehicle.Vehicle, fromCache bool) APIVehicle {
-	return APIVehicle{strconv.FormatUint(veh.MetaData.Hash, 10), veh.MetaData.Country.String(), veh.Type.String(), veh.RegNr, veh.VIN, veh.Brand, veh.Model, veh.Variant, veh.FuelType, veh.FirstRegDate.Format(dateFmt), fromCache}
+// Enumerated values such as vehicle type, fuel type and registration status are translated into the given language.
+func vehicleToAPIType(veh vehicle.Vehicle, fromCache bool, lang locale.Lang) APIVehicle {
+	var statusDate string
+	if !veh.RegStatusDate.IsZero() {
+		statusDate = veh.RegStatusDate.Format(dateFmt)
+	}
+	return APIVehicle{strconv.FormatUint(veh.MetaData.Hash, 10), veh.MetaData.Country.String(), lang.Type(veh.Type), veh.RegNr, veh.VIN, veh.Brand, veh.Model, veh.Variant, veh.FuelType, lang.Fuel(veh.NormalisedFuel()), veh.FirstRegDate.Format(dateFmt), lang.RegStatus(veh.RegStatus), statusDate, APITechData(veh.Tech), veh.MetaData.Deregistered, fromCache, ""}
 }
 
 // handleLookup allows vehicle lookups based on hash value, VIN or registration number. A country must always be
-// provided.
+// provided. If the query parameter "registered" is set to "true", deregistered vehicles are treated as not found.
+// Enumerated values are translated according to the "lang" query parameter or the Accept-Language header.
 // @TODO: There is too much business logic here; put it somewhere else.
 func (srv *WebServer) handleLookup(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -43,6 +69,7 @@ func (srv *WebServer) handleLookup(w http.ResponseWriter, r *http.Request) {
 	hash := r.URL.Query().Get("hash")
 	regNr := r.URL.Query().Get("regnr")
 	vin := r.URL.Query().Get("vin")
+	onlyRegistered := r.URL.Query().Get("registered") == "true"
 	regCountry := vehicle.RegCountryFromString(country) // For now, we're forcing unknown countries into "DK".
 	if regNr == "" && vin == "" && hash == "" {
 		srv.JSONError(w, APIError{http.StatusBadRequest, errLookup, "Missing query parameter 'hash', 'regnr' or 'vin'"})
@@ -54,8 +81,9 @@ func (srv *WebServer) handleLookup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var (
-		veh vehicle.Vehicle
-		err error
+		veh     vehicle.Vehicle
+		service string // Name of the lookup service that answered, if any.
+		err     error
 	)
 	if hash != "" {
 		veh, err = srv.store.LookupByHash(hash)
@@ -69,42 +97,53 @@ func (srv *WebServer) handleLookup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if veh == (vehicle.Vehicle{}) {
-		// No cached result, so we attempt a direct lookup.
-		mngr := srv.lookupMngr.FindServiceByCountry(regCountry)
-		if mngr == nil {
-			// No manager exists for that country, so let's give up.
-			fmt.Printf("No direct lookups supported for country %s\n", regCountry.String())
-			w.WriteHeader(http.StatusNotFound)
-			return
-		}
+		// No cached result, so we attempt a direct lookup via each service that supports the country, in order of
+		// priority.
 		if regNr != "" {
-			fmt.Printf("Cache miss: performing direct lookup of reg.nr. %s via %q\n", regNr, mngr.Name())
-			veh, err = mngr.LookupRegNr(regNr)
+			fmt.Printf("Cache miss: performing direct lookup of reg.nr. %s\n", regNr)
+			veh, service, err = srv.lookupMngr.LookupRegNr(regCountry, regNr)
 		} else {
-			fmt.Printf("Cache miss: performing direct lookup of VIN %s via %q\n", vin, mngr.Name())
-			veh, err = mngr.LookupVIN(vin)
+			fmt.Printf("Cache miss: performing direct lookup of VIN %s\n", vin)
+			veh, service, err = srv.lookupMngr.LookupVIN(regCountry, vin)
+		}
+		if err == extlookup.ErrNoService || err == vehicle.ErrNoSuchVehicle {
+			fmt.Printf("Direct lookup in country %s: %s\n", regCountry.String(), err)
+			w.WriteHeader(http.StatusNotFound)
+			return
 		}
 		if err != nil {
 			srv.JSONError(w, APIError{http.StatusInternalServerError, errLookup, err.Error()})
 			return
 		}
-		// At this point, we found a vehicle via direct lookup. Let's cache it for future lookups!
+		fmt.Printf("Direct lookup answered by %q\n", service)
+		// At this point, we found a vehicle via direct lookup. Let's cache it for future lookups, unless it violates
+		// a data-quality rule.
 		if err = veh.GenHash(); err != nil {
 			// We just print the error. It doesn't prevent the request from completing.
 			fmt.Printf("Error generating hash for vehicle with Ident %d\n", veh.MetaData.Ident)
-		} else {
-			if _, err := srv.store.SyncVehicle(veh); err != nil {
-				// Again, we don't let this error interrupt the request.
+		} else if _, err := srv.store.SyncVehicle(veh); err != nil {
+			// Again, we don't let this error interrupt the request.
+			if rejErr, ok := err.(vehicle.RejectedError); ok {
+				fmt.Printf("Not caching vehicle with Ident %d: %s\n", veh.MetaData.Ident, rejErr)
+			} else {
 				fmt.Printf("Unable to add vehicle with Ident %d\n", veh.MetaData.Ident)
 			}
 		}
 		fromCache = false
 	}
-	bytes, err := json.Marshal(vehicleToAPIType(veh, fromCache))
+	if onlyRegistered && veh.IsDeregistered() {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	lang := requestLang(r)
+	apiVeh := vehicleToAPIType(veh, fromCache, lang)
+	apiVeh.LookupService = service
+	bytes, err := json.Marshal(apiVeh)
 	if err != nil {
 		srv.JSONError(w, APIError{http.StatusInternalServerError, errMarshalling, err.Error()})
 	}
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", string(lang))
 	w.WriteHeader(http.StatusOK)
 	w.Write(bytes)
 }
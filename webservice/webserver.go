@@ -12,6 +12,7 @@ import (
 
 	"github.com/mkock/autobot/config"
 	"github.com/mkock/autobot/extlookup"
+	"github.com/mkock/autobot/locale"
 	"github.com/mkock/autobot/scheduler"
 	"github.com/mkock/autobot/vehicle"
 )
@@ -27,6 +28,8 @@ const (
 	errLookup
 	errMarshalling
 	errVehicleOp
+	errInspections
+	errBrands
 )
 
 // WebServer represents the REST-API part of autobot.
@@ -34,6 +37,7 @@ type WebServer struct {
 	startTime  time.Time
 	store      *vehicle.Store
 	lookupMngr *extlookup.Manager
+	catalogue  *vehicle.Catalogue
 	cnf        config.Config
 }
 
@@ -45,8 +49,8 @@ type APIError struct {
 }
 
 // New initialises a new webserver. You need to start it by calling Serve().
-func New(store *vehicle.Store, mngr *extlookup.Manager, cnf config.Config) *WebServer {
-	return &WebServer{time.Now(), store, mngr, cnf}
+func New(store *vehicle.Store, mngr *extlookup.Manager, cat *vehicle.Catalogue, cnf config.Config) *WebServer {
+	return &WebServer{time.Now(), store, mngr, cat, cnf}
 }
 
 // JSONError serves the given error as JSON.
@@ -64,6 +68,15 @@ func (srv *WebServer) JSONError(w http.ResponseWriter, handlerErr APIError) {
 	fmt.Fprint(w, string(d))
 }
 
+// requestLang returns the language that enumerated values should be translated into. The query parameter "lang"
+// takes precedence over the Accept-Language header.
+func requestLang(r *http.Request) locale.Lang {
+	if lang, ok := locale.Parse(r.URL.Query().Get("lang")); ok {
+		return lang
+	}
+	return locale.FromAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
 // logRequest prints the HTTP method and URL to stdout.
 func (srv *WebServer) logRequest(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -97,6 +110,9 @@ func (srv *WebServer) setupMux() {
 	http.HandleFunc("/vehiclestore/status", srv.logResponse(srv.handleStoreStatus)) // GET.
 	http.HandleFunc("/lookup", srv.logResponse(srv.handleLookup))                   // GET.
 	http.HandleFunc("/vehicle", srv.logResponse(srv.handleVehicle))                 // PATCH.
+	http.HandleFunc("/vehicle/inspections", srv.logResponse(srv.handleInspections)) // GET.
+	http.HandleFunc("/brands", srv.logResponse(srv.handleBrands))                   // GET.
+	http.HandleFunc("/brands/", srv.logResponse(srv.handleBrands))                  // GET.
 }
 
 // Serve starts the web server. It never returns unless interrupted.
@@ -112,7 +128,7 @@ func (srv *WebServer) Serve(port uint, sync bool) error {
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
 	if sync {
 		// Start a go routine with the scheduler.
-		sched := scheduler.New(srv.cnf, srv.store, os.Stdout)
+		sched := scheduler.New(srv.cnf, srv.store, srv.catalogue, os.Stdout)
 		stop, err := sched.Start()
 		if err != nil {
 			return err // This will happen if the time expression from the config file couldn't be parsed.